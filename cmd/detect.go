@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ajaysinghnp/maya-cli/internal/httpclient"
+	"github.com/ajaysinghnp/maya-cli/internal/logger"
+	"github.com/ajaysinghnp/maya-cli/internal/provider"
+	_ "github.com/ajaysinghnp/maya-cli/internal/provider/all" // built-in providers
+	"github.com/spf13/cobra"
+)
+
+// detectCmd represents the detect command
+var detectCmd = &cobra.Command{
+	Use:   "detect <url>",
+	Short: "Show which provider maya would use for a URL",
+	Long: `Detect prints which provider maya picks for a URL and why.
+
+URLs are matched by host and path first. When no provider claims a URL,
+maya fetches its headers and first bytes to recognize the content, e.g.
+playlists served from .txt gateways. Use --no-sniff to skip that request.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		url := args[0]
+		verbose, _ := cmd.Flags().GetBool("verbose")
+		noSniff, _ := cmd.Flags().GetBool("no-sniff")
+		log := logger.New(verbose, "")
+		defer log.Close()
+
+		var client *httpclient.Client
+		if !noSniff {
+			client = httpclient.Default()
+		}
+
+		d, err := provider.Detect(cmd.Context(), client, url)
+		if err != nil {
+			log.Error(err.Error())
+			os.Exit(ExitFailure)
+		}
+
+		log.Success("Provider: " + d.Provider.Name())
+		log.Info("Reason: " + d.Reason())
+		log.Info(fmt.Sprintf("Host: %s | Path: %s", d.URL.Host, d.URL.EscapedPath()))
+		if d.FinalURL != "" && d.FinalURL != url {
+			log.Info("Final URL: " + d.FinalURL)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(detectCmd)
+
+	detectCmd.Flags().Bool("no-sniff", false, "Only match by URL, never fetch the content")
+}
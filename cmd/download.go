@@ -1,17 +1,21 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"os"
 
+	"github.com/ajaysinghnp/maya-cli/internal/batch"
 	"github.com/ajaysinghnp/maya-cli/internal/downloader"
+	"github.com/ajaysinghnp/maya-cli/internal/httpclient"
 	"github.com/ajaysinghnp/maya-cli/internal/logger"
 	"github.com/spf13/cobra"
 )
 
 // downloadCmd represents the download command
 var downloadCmd = &cobra.Command{
-	Use:   "download <url>",
-	Short: "Download movies or series from a given URL",
+	Use:   "download <url>... | --batch <file>",
+	Short: "Download movies or series from one or more URLs",
 	Long: `Download is a flexible command to fetch movies or TV series
 from supported sources. It handles:
 
@@ -27,33 +31,95 @@ Examples:
 
   # Download a series
   maya download https://example.com/series456
+
+  # Download a list of URLs, two at a time ("-" reads the list from stdin)
+  maya download --batch urls.txt --jobs 2
+  cat urls.txt | maya download -
+
+  A batch file has one URL per line; blank lines and # comments are skipped.
+  key=value overrides after a URL apply to that line only:
+
+    https://example.com/movie123 output=/media/movies source-label="No Ads"
+    https://example.com/series456 season=2 episodes=1-5 quality=720p
+
+  # Pin a rendition when the source offers several
+  maya download https://example.com/master.m3u8 --quality 720p
+  maya download https://example.com/master.m3u8 --max-bandwidth 3M --prefer-codec h264
+
+  # Also save Hindi and English audio plus English subtitles
+  maya download https://example.com/master.m3u8 --audio hin,eng --subs eng
+
+  # Record a live event for two hours (Ctrl-C stops early and keeps what was recorded)
+  maya download https://example.com/live.m3u8 --live --duration 2h
+
+  # Stay under 2 MiB/s on a shared link
+  maya download https://example.com/master.m3u8 --limit-rate 2M
+
+  # Go through a SOCKS proxy and send a Referer to the CDN
+  maya download https://example.com/master.m3u8 --proxy socks5://127.0.0.1:1080 --host-referer cdn.example.com=https://player.example.com/
+
+  # Mux everything into one MKV with ffmpeg
+  maya download https://example.com/master.m3u8 --audio all --subs all --postprocess ffmpeg --container mkv
 `,
-	Args: cobra.MinimumNArgs(1), // requires at least one argument (the URL)
+	Args: cobra.ArbitraryArgs, // URLs, "-" for stdin, or none with --batch
 	Run: func(cmd *cobra.Command, args []string) {
-		url := args[0]
-
 		verbose, _ := cmd.Flags().GetBool("verbose")
-		output, _ := cmd.Flags().GetString("output")
-		resume, _ := cmd.Flags().GetBool("resume")
-		concurrency, _ := cmd.Flags().GetInt("concurrency")
+		live, _ := cmd.Flags().GetBool("live")
+		duration, _ := cmd.Flags().GetDuration("duration")
+		batchFile, _ := cmd.Flags().GetString("batch")
 		log := logger.New(verbose, "")
 		defer log.Close()
 
-		if verbose {
-			log.Debug("Starting download for URL: " + url)
-			log.Debug("Output: " + output)
-			log.Debug(fmt.Sprintf("Resume: %v", resume))
-			log.Debug(fmt.Sprintf("Concurrency: %d", concurrency))
+		log.Debug(fmt.Sprintf("URLs: %v | Batch: %q", args, batchFile))
+		log.Debug(fmt.Sprintf("Live: %v | Duration: %s", live, duration))
+
+		entries, fromStdin, err := downloadEntries(args, batchFile)
+		if err != nil {
+			log.Error(err.Error())
+			os.Exit(ExitFailure)
 		}
 
-		log.Info("Analyzing URL: " + url)
+		opts := downloader.Options{Live: live, Duration: duration}
+		if err := readSelection(cmd, &opts, !fromStdin, log); err != nil {
+			log.Error(err.Error())
+			os.Exit(ExitFailure)
+		}
+		readMeta(cmd, &opts, log)
+		if err := readHTTP(cmd, &opts, log); err != nil {
+			log.Error(err.Error())
+			os.Exit(ExitFailure)
+		}
+		jobs, err := readRun(cmd, &opts, log)
+		if err != nil {
+			log.Error(err.Error())
+			os.Exit(ExitFailure)
+		}
+		// one client for every job, so the rate limits cap them together
+		if opts.Client, err = httpclient.New(opts.HTTP, log); err != nil {
+			log.Error(err.Error())
+			os.Exit(ExitFailure)
+		}
 
 		// Create downloader
-		dl := downloader.New(log)
-		err := dl.StartDownload(url, output, resume, concurrency)
-		if err != nil {
-			log.Error(fmt.Sprintf("Download failed: %v", err))
-			return
+		runner := &batch.Runner{Downloader: downloader.New(log), Log: log, Jobs: jobs}
+		results := runner.Run(cmd.Context(), entries, opts)
+
+		failed := batch.Count(results, batch.StatusFailed)
+		interrupted := batch.Count(results, batch.StatusInterrupted) + batch.Count(results, batch.StatusSkipped)
+		if len(results) > 1 {
+			fmt.Println()
+			batch.WriteSummary(os.Stdout, results)
+			fmt.Println()
+			log.Info(fmt.Sprintf("%d done, %d failed, %d interrupted or skipped",
+				batch.Count(results, batch.StatusDone), failed, interrupted))
+		}
+
+		switch {
+		case interrupted > 0:
+			log.Warn("Download interrupted, run the same command again to resume")
+			os.Exit(ExitInterrupted)
+		case failed > 0:
+			os.Exit(ExitFailure)
 		}
 
 		// Use Success for completed download
@@ -61,12 +127,59 @@ Examples:
 	},
 }
 
+// downloadEntries collects the URLs from the arguments and the --batch file,
+// where "-" in either place reads a list from stdin
+func downloadEntries(args []string, batchFile string) ([]batch.Entry, bool, error) {
+	var (
+		entries   []batch.Entry
+		fromStdin bool
+		urls      []string
+	)
+
+	for _, a := range args {
+		if a == batch.Stdin {
+			fromStdin = true
+			continue
+		}
+		urls = append(urls, a)
+	}
+	entries = append(entries, batch.FromArgs(urls)...)
+
+	if batchFile == batch.Stdin {
+		fromStdin = true
+	} else if batchFile != "" {
+		list, err := batch.ReadFile(batchFile)
+		if err != nil {
+			return nil, false, err
+		}
+		entries = append(entries, list...)
+	}
+
+	if fromStdin {
+		list, err := batch.ReadFile(batch.Stdin)
+		if err != nil {
+			return nil, false, err
+		}
+		entries = append(entries, list...)
+	}
+
+	if len(entries) == 0 {
+		return nil, false, errors.New("no URLs to download (pass URLs, --batch <file> or - for stdin)")
+	}
+	return entries, fromStdin, nil
+}
+
 func init() {
 	// Attach the download command to root
 	rootCmd.AddCommand(downloadCmd)
 
 	// Flags specific to the download command
-	downloadCmd.Flags().BoolP("resume", "r", true, "Resume an interrupted download if cached files exist")
-	downloadCmd.Flags().StringP("output", "o", "", "Specify output directory or filename (default: auto-generated)")
-	downloadCmd.Flags().IntP("concurrency", "c", 5, "Number of simultaneous downloads for series episodes")
+	downloadCmd.Flags().StringP("batch", "b", "", "File with URLs to download, one per line with optional key=value overrides (- for stdin)")
+	downloadCmd.Flags().Bool("live", false, "Record live/event playlists (no EXT-X-ENDLIST) until they end, --duration passes or Ctrl-C")
+	downloadCmd.Flags().Duration("duration", 0, "Stop a live recording after this long (e.g. 90m, 2h)")
+
+	addSelectionFlags(downloadCmd)
+	addMetaFlags(downloadCmd)
+	addHTTPFlags(downloadCmd)
+	addRunFlags(downloadCmd)
 }
@@ -0,0 +1,287 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ajaysinghnp/maya-cli/internal/downloader"
+	"github.com/ajaysinghnp/maya-cli/internal/downloader/m3u8"
+	"github.com/ajaysinghnp/maya-cli/internal/httpclient"
+	"github.com/ajaysinghnp/maya-cli/internal/logger"
+	"github.com/ajaysinghnp/maya-cli/internal/metadata"
+	"github.com/ajaysinghnp/maya-cli/internal/progress"
+	"github.com/ajaysinghnp/maya-cli/internal/provider"
+	"github.com/ajaysinghnp/maya-cli/internal/retry"
+	"github.com/ajaysinghnp/maya-cli/utils"
+	"github.com/spf13/cobra"
+)
+
+// Flag groups shared by download and the queue commands. Each add*Flags
+// registers a group and the matching read function fills it into
+// downloader.Options.
+
+// addSelectionFlags registers what is downloaded and how it is saved
+func addSelectionFlags(cmd *cobra.Command) {
+	f := cmd.Flags()
+	f.StringP("output", "o", "", "Specify output directory or filename (default: auto-generated)")
+	f.StringP("quality", "q", "best", "Preferred variant for multi-quality streams (best, worst, 1080p, 720p, ...)")
+	f.String("max-bandwidth", "", "Skip variants above this bitrate (e.g. 800k, 3M)")
+	f.String("prefer-codec", "", "Prefer variants using this codec (h264, hevc, av1, ...)")
+	f.StringSlice("audio", nil, "Alternate audio languages to save next to the video (e.g. hin,eng or all)")
+	f.StringSlice("subs", nil, "Subtitle languages to save as .srt next to the video (e.g. eng or all)")
+	f.Int("season", 0, "Season to download from a series page (default: all), or the season of a direct M3U8 episode")
+	f.String("episodes", "", "Episodes to download from a series page, e.g. 1-5,8 (default: all)")
+	f.String("source-label", "", "Pick the MoviesBazar source whose label or tag contains this text (e.g. \"No Ads\")")
+	f.Int("source-index", 0, "Pick the MoviesBazar source by its position in the list, starting at 1")
+	f.StringSlice("prefer-lang", nil, "Prefer MoviesBazar sources in these languages, in order (e.g. hin,eng)")
+	f.BoolP("yes", "y", false, "Never prompt; pick the best matching source and fail on missing metadata (implied when stdin is not a terminal)")
+	f.Bool("nfo", true, "Write Jellyfin/Kodi .nfo files (movie.nfo, or tvshow.nfo, season.nfo and the episode .nfo)")
+	f.Bool("artwork", true, "Save the poster (poster/folder), fanart and episode thumbnail next to the media")
+	f.String("postprocess", "none", "Post-processing stage: none, ffmpeg (required) or auto (ffmpeg when installed)")
+	f.String("container", "mp4", "Output container: mp4 or mkv (mkv requires --postprocess ffmpeg)")
+}
+
+// addMetaFlags registers the manual metadata for direct M3U8 links
+func addMetaFlags(cmd *cobra.Command) {
+	f := cmd.Flags()
+	f.String("meta", "", "JSON file with metadata for direct M3U8 links (title, releaseYear, type, season, episode, ids)")
+	f.String("title", "", "Title for direct M3U8 links (overrides --meta)")
+	f.Int("year", 0, "Release year for direct M3U8 links")
+	f.String("type", "", "Media type for direct M3U8 links: movie or series")
+	f.Int("episode", 0, "Episode number of a series episode")
+	f.String("tmdb", "", "TMDB id for direct M3U8 links")
+	f.String("imdb", "", "IMDB id for direct M3U8 links (e.g. tt1234567)")
+}
+
+// addHTTPFlags registers retries, headers, proxy and rate limits
+func addHTTPFlags(cmd *cobra.Command) {
+	f := cmd.Flags()
+	defaults := retry.Default()
+	f.Int("retries", defaults.Attempts-1, "Retries for failed page, playlist, key and segment requests")
+	f.Duration("retry-delay", defaults.BaseDelay, "Wait before the first retry, doubled on every further one")
+	f.Duration("retry-max-delay", defaults.MaxDelay, "Upper bound for the retry backoff (Retry-After headers are always honored)")
+	f.IntSlice("retry-on", defaults.RetryOn, "HTTP statuses that are retried")
+	f.Duration("timeout", defaults.Timeout, "Time limit for a single request attempt including the body (0 = none)")
+	f.String("header-profile", httpclient.DefaultProfile,
+		"Browser header profile for requests ("+strings.Join(httpclient.ProfileNames(), ", ")+")")
+	f.StringArray("header", nil, "Extra request header \"Name: value\" (repeatable)")
+	f.StringArray("host-referer", nil, "Referer (and matching Origin) for a host and its subdomains, host=url (repeatable)")
+	f.String("proxy", "", "HTTP, HTTPS or SOCKS5 proxy URL (default: HTTP_PROXY/HTTPS_PROXY environment)")
+	f.Bool("insecure", false, "Skip TLS certificate verification")
+	f.String("ca-cert", "", "PEM file with extra trusted CA certificates")
+	f.Duration("connect-timeout", 30*time.Second, "Time limit for connecting, the TLS handshake and response headers (0 = none)")
+	f.String("limit-rate", "", "Cap the total download rate across all workers (e.g. 500k, 2M; binary units)")
+	f.StringArray("limit-rate-host", nil, "Cap the download rate from a host and its subdomains, host=rate (repeatable)")
+}
+
+// addRunFlags registers how a download runs: resume, concurrency and progress
+func addRunFlags(cmd *cobra.Command) {
+	f := cmd.Flags()
+	f.BoolP("resume", "r", true, "Resume an interrupted download if cached files exist")
+	f.IntP("concurrency", "c", 5, "Number of simultaneous downloads for series episodes")
+	f.Int("segment-concurrency", 5, "Number of segments fetched at the same time for each download")
+	f.IntP("jobs", "j", 1, "Number of URLs downloaded at the same time")
+	f.String("progress", "auto", "Progress display: auto (bar on a terminal, log lines otherwise), bar, log or none")
+}
+
+// readSelection fills in the addSelectionFlags group. Prompts are only
+// allowed when stdin is a free terminal and --yes was not given.
+func readSelection(cmd *cobra.Command, opts *downloader.Options, stdinFree bool, log *logger.Logger) error {
+	f := cmd.Flags()
+	output, _ := f.GetString("output")
+	quality, _ := f.GetString("quality")
+	maxBandwidthStr, _ := f.GetString("max-bandwidth")
+	preferCodec, _ := f.GetString("prefer-codec")
+	audio, _ := f.GetStringSlice("audio")
+	subs, _ := f.GetStringSlice("subs")
+	season, _ := f.GetInt("season")
+	episodesStr, _ := f.GetString("episodes")
+	sourceLabel, _ := f.GetString("source-label")
+	sourceIndex, _ := f.GetInt("source-index")
+	preferLang, _ := f.GetStringSlice("prefer-lang")
+	yes, _ := f.GetBool("yes")
+	nfo, _ := f.GetBool("nfo")
+	art, _ := f.GetBool("artwork")
+	postProcess, _ := f.GetString("postprocess")
+	container, _ := f.GetString("container")
+
+	log.Debug("Output: " + output)
+	log.Debug(fmt.Sprintf("Quality: %q | Max bandwidth: %q | Prefer codec: %q", quality, maxBandwidthStr, preferCodec))
+	log.Debug(fmt.Sprintf("Audio: %v | Subtitles: %v | Season: %d | Episodes: %q", audio, subs, season, episodesStr))
+	log.Debug(fmt.Sprintf("Source label: %q | Source index: %d | Prefer lang: %v | Yes: %v", sourceLabel, sourceIndex, preferLang, yes))
+	log.Debug(fmt.Sprintf("NFO: %v | Artwork: %v | Post-process: %q | Container: %q", nfo, art, postProcess, container))
+
+	maxBandwidth, err := utils.ParseBitrate(maxBandwidthStr)
+	if err != nil {
+		return err
+	}
+
+	episodes, err := metadata.ParseEpisodes(episodesStr)
+	if err != nil {
+		return err
+	}
+
+	if sourceIndex < 0 {
+		return fmt.Errorf("invalid --source-index %d (sources are numbered from 1)", sourceIndex)
+	}
+
+	// without a terminal there is nobody to answer a prompt
+	interactive := !yes && stdinFree && utils.IsTerminal(os.Stdin)
+
+	opts.Output = output
+	opts.Select = m3u8.Selection{
+		Quality:      quality,
+		MaxBandwidth: maxBandwidth,
+		PreferCodec:  preferCodec,
+		Audio:        audio,
+		Subtitles:    subs,
+	}
+	opts.Source = provider.SourceSelection{
+		Label:      sourceLabel,
+		Index:      sourceIndex,
+		PreferLang: preferLang,
+		Auto:       !interactive,
+	}
+	opts.Season = season
+	opts.Episodes = episodes
+	opts.Meta.Season = season
+	opts.Meta.Prompt = interactive
+	opts.NFO = nfo
+	opts.Artwork = art
+	opts.Container = container
+	opts.PostProcess = postProcess
+	return nil
+}
+
+// readMeta fills in the addMetaFlags group
+func readMeta(cmd *cobra.Command, opts *downloader.Options, log *logger.Logger) {
+	f := cmd.Flags()
+	metaFile, _ := f.GetString("meta")
+	title, _ := f.GetString("title")
+	year, _ := f.GetInt("year")
+	mediaType, _ := f.GetString("type")
+	episode, _ := f.GetInt("episode")
+	tmdb, _ := f.GetString("tmdb")
+	imdb, _ := f.GetString("imdb")
+
+	log.Debug(fmt.Sprintf("Meta file: %q | Title: %q | Year: %d | Type: %q | Episode: %d | TMDB: %q | IMDB: %q",
+		metaFile, title, year, mediaType, episode, tmdb, imdb))
+
+	opts.Meta.File = metaFile
+	opts.Meta.Title = title
+	opts.Meta.Year = year
+	opts.Meta.Type = metadata.MediaType(mediaType)
+	opts.Meta.Episode = episode
+	opts.Meta.TMDB = tmdb
+	opts.Meta.IMDB = imdb
+}
+
+// readHTTP fills in the addHTTPFlags group
+func readHTTP(cmd *cobra.Command, opts *downloader.Options, log *logger.Logger) error {
+	f := cmd.Flags()
+	retries, _ := f.GetInt("retries")
+	retryDelay, _ := f.GetDuration("retry-delay")
+	retryMaxDelay, _ := f.GetDuration("retry-max-delay")
+	retryOn, _ := f.GetIntSlice("retry-on")
+	timeout, _ := f.GetDuration("timeout")
+	headerProfile, _ := f.GetString("header-profile")
+	headerFlags, _ := f.GetStringArray("header")
+	hostReferers, _ := f.GetStringArray("host-referer")
+	proxy, _ := f.GetString("proxy")
+	insecure, _ := f.GetBool("insecure")
+	caCert, _ := f.GetString("ca-cert")
+	connectTimeout, _ := f.GetDuration("connect-timeout")
+	limitRateStr, _ := f.GetString("limit-rate")
+	hostLimitFlags, _ := f.GetStringArray("limit-rate-host")
+
+	log.Debug(fmt.Sprintf("Header profile: %q | Headers: %v | Host referers: %v | Proxy: %q | Insecure: %v | Connect timeout: %s",
+		headerProfile, headerFlags, hostReferers, proxy, insecure, connectTimeout))
+	log.Debug(fmt.Sprintf("Limit rate: %q | Host limits: %v", limitRateStr, hostLimitFlags))
+	log.Debug(fmt.Sprintf("Retries: %d | Delay: %s..%s | Retry on: %v | Timeout: %s",
+		retries, retryDelay, retryMaxDelay, retryOn, timeout))
+
+	limitRate, err := utils.ParseByteSize(limitRateStr)
+	if err != nil {
+		return err
+	}
+
+	config := httpclient.Config{
+		Profile:        headerProfile,
+		Headers:        http.Header{},
+		Proxy:          proxy,
+		Insecure:       insecure,
+		CACert:         caCert,
+		ConnectTimeout: connectTimeout,
+		RateLimit:      limitRate,
+		Retry: retry.Policy{
+			Attempts:  retries + 1,
+			BaseDelay: retryDelay,
+			MaxDelay:  retryMaxDelay,
+			RetryOn:   retryOn,
+			Timeout:   timeout,
+		},
+	}
+	for _, h := range headerFlags {
+		name, value, err := httpclient.ParseHeader(h)
+		if err != nil {
+			return err
+		}
+		config.Headers.Add(name, value)
+	}
+	for _, r := range hostReferers {
+		rule, err := httpclient.ParseHostRule(r)
+		if err != nil {
+			return err
+		}
+		config.HostRules = append(config.HostRules, rule)
+	}
+	for _, l := range hostLimitFlags {
+		limit, err := httpclient.ParseHostLimit(l)
+		if err != nil {
+			return err
+		}
+		config.HostRateLimits = append(config.HostRateLimits, limit)
+	}
+	if insecure {
+		log.Warn("TLS certificate verification is disabled")
+	}
+
+	opts.HTTP = config
+	return nil
+}
+
+// readRun fills in the addRunFlags group and returns the --jobs limit
+func readRun(cmd *cobra.Command, opts *downloader.Options, log *logger.Logger) (int, error) {
+	f := cmd.Flags()
+	resume, _ := f.GetBool("resume")
+	concurrency, _ := f.GetInt("concurrency")
+	segmentConcurrency, _ := f.GetInt("segment-concurrency")
+	jobs, _ := f.GetInt("jobs")
+	progressMode, _ := f.GetString("progress")
+
+	log.Debug(fmt.Sprintf("Resume: %v | Concurrency: %d jobs, %d episodes, %d segments",
+		resume, jobs, concurrency, segmentConcurrency))
+
+	var reporter progress.Reporter
+	switch progressMode {
+	case "auto":
+		reporter = progress.New(log, utils.IsTerminal(os.Stdout))
+	case "bar":
+		reporter = progress.New(log, true)
+	case "log":
+		reporter = progress.New(log, false)
+	case "none":
+		reporter = progress.Nop{}
+	default:
+		return 0, fmt.Errorf("invalid --progress %q (use auto, bar, log or none)", progressMode)
+	}
+
+	opts.Resume = resume
+	opts.Parallel = concurrency
+	opts.Concurrent = segmentConcurrency
+	opts.Progress = reporter
+	return jobs, nil
+}
@@ -0,0 +1,410 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ajaysinghnp/maya-cli/internal/downloader"
+	"github.com/ajaysinghnp/maya-cli/internal/httpclient"
+	"github.com/ajaysinghnp/maya-cli/internal/logger"
+	"github.com/ajaysinghnp/maya-cli/internal/queue"
+	"github.com/spf13/cobra"
+)
+
+// queueCmd groups the queue subcommands
+var queueCmd = &cobra.Command{
+	Use:   "queue",
+	Short: "Queue downloads now and run them later",
+	Long: `Queue keeps a list of downloads in a file under your data dir, so a
+long list of movies can be added during the day and drained overnight.
+
+Metadata is resolved when an item is added and stored with the selection
+flags (quality, source, output, ...). Transfer settings such as --jobs,
+--limit-rate or --proxy are given to queue run. Items survive restarts, and
+Ctrl-C during queue run puts the running items back in the queue. Several
+runs can drain one queue; items of a run that was killed are queued again
+once its process is gone or it stops reporting in.
+
+Examples:
+  maya queue add https://example.com/movie123 --quality 720p
+  maya queue add --batch urls.txt
+  maya queue list
+  maya queue pause 3
+  maya queue run --jobs 2 --limit-rate 2M
+`,
+}
+
+var queueAddCmd = &cobra.Command{
+	Use:   "add <url>... | --batch <file>",
+	Short: "Resolve URLs and add them to the queue",
+	Args:  cobra.ArbitraryArgs, // URLs, "-" for stdin, or none with --batch
+	Run: func(cmd *cobra.Command, args []string) {
+		verbose, _ := cmd.Flags().GetBool("verbose")
+		batchFile, _ := cmd.Flags().GetString("batch")
+		paused, _ := cmd.Flags().GetBool("paused")
+		log := logger.New(verbose, "")
+		defer log.Close()
+
+		q, err := openQueue(cmd)
+		if err != nil {
+			log.Error(err.Error())
+			os.Exit(ExitFailure)
+		}
+
+		entries, fromStdin, err := downloadEntries(args, batchFile)
+		if err != nil {
+			log.Error(err.Error())
+			os.Exit(ExitFailure)
+		}
+
+		var base downloader.Options
+		if err := readSelection(cmd, &base, !fromStdin, log); err != nil {
+			log.Error(err.Error())
+			os.Exit(ExitFailure)
+		}
+		readMeta(cmd, &base, log)
+		if err := readHTTP(cmd, &base, log); err != nil {
+			log.Error(err.Error())
+			os.Exit(ExitFailure)
+		}
+
+		status := queue.Queued
+		if paused {
+			status = queue.Paused
+		}
+
+		ctx := cmd.Context()
+		dl := downloader.New(log)
+		failed := 0
+		for _, e := range entries {
+			opts := e.Options(base)
+			// queue run may start in another directory, so pin the output
+			// (the current directory by default) to an absolute path
+			if opts.Output, err = filepath.Abs(opts.Output); err != nil {
+				log.Error(err.Error())
+				os.Exit(ExitFailure)
+			}
+
+			name, meta, err := dl.Resolve(ctx, e.URL, opts)
+			if ctx.Err() != nil {
+				log.Warn("Interrupted, the remaining URLs were not added")
+				os.Exit(ExitInterrupted)
+			}
+			if err != nil {
+				log.Error(fmt.Sprintf("Not queued: %s: %v", e.URL, err))
+				failed++
+				continue
+			}
+
+			it := &queue.Item{
+				URL:      e.URL,
+				Provider: name,
+				Metadata: meta,
+				Choices:  queue.ChoicesFrom(opts),
+				Status:   status,
+				Added:    time.Now(),
+			}
+			err = q.Update(func(f *queue.File) error {
+				for _, old := range f.Items {
+					if old.URL == it.URL && old.Choices.Episodes == it.Choices.Episodes &&
+						old.Choices.Season == it.Choices.Season && old.Status != queue.Done {
+						return fmt.Errorf("already queued as item %d (%s)", old.ID, old.Status)
+					}
+				}
+				f.Add(it)
+				return nil
+			})
+			if err != nil {
+				log.Warn(fmt.Sprintf("Not queued: %s: %v", e.URL, err))
+				continue
+			}
+			log.Success(fmt.Sprintf("Queued item %d: %s", it.ID, it.Label()))
+		}
+
+		if failed > 0 {
+			os.Exit(ExitFailure)
+		}
+	},
+}
+
+var queueListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Show the queued items",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		verbose, _ := cmd.Flags().GetBool("verbose")
+		statusFilter, _ := cmd.Flags().GetString("status")
+		log := logger.New(verbose, "")
+		defer log.Close()
+
+		q, err := openQueue(cmd)
+		if err != nil {
+			log.Error(err.Error())
+			os.Exit(ExitFailure)
+		}
+		f, err := q.Load()
+		if err != nil {
+			log.Error(err.Error())
+			os.Exit(ExitFailure)
+		}
+
+		var items []*queue.Item
+		for _, it := range f.Items {
+			if statusFilter == "" || string(it.Status) == statusFilter {
+				items = append(items, it)
+			}
+		}
+		if len(items) == 0 {
+			log.Info("Queue is empty (" + q.Path + ")")
+			return
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tSTATUS\tTITLE\tSELECTION\tTRIES\tADDED\tURL\tERROR")
+		for _, it := range items {
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%d\t%s\t%s\t%s\n",
+				it.ID, it.Status, it.Label(), selectionLabel(it), it.Attempts,
+				it.Added.Format("2006-01-02 15:04"), it.URL, it.Error)
+		}
+		tw.Flush()
+	},
+}
+
+var queueRemoveCmd = &cobra.Command{
+	Use:   "remove <id>... | --finished",
+	Short: "Remove items from the queue",
+	Run: func(cmd *cobra.Command, args []string) {
+		finished, _ := cmd.Flags().GetBool("finished")
+		updateItems(cmd, args, finished, func(f *queue.File, it *queue.Item) error {
+			if it.Status == queue.Running && !it.Abandoned() {
+				return errors.New("it is running")
+			}
+			f.Remove(it.ID)
+			return nil
+		}, func(it *queue.Item) bool { return it.Status == queue.Done }, "Removed")
+	},
+}
+
+var queuePauseCmd = &cobra.Command{
+	Use:   "pause <id>... | --all",
+	Short: "Keep queued items but skip them in queue run",
+	Run: func(cmd *cobra.Command, args []string) {
+		all, _ := cmd.Flags().GetBool("all")
+		updateItems(cmd, args, all, func(_ *queue.File, it *queue.Item) error {
+			if it.Status != queue.Queued {
+				return fmt.Errorf("it is %s, not queued", it.Status)
+			}
+			it.Status = queue.Paused
+			return nil
+		}, func(it *queue.Item) bool { return it.Status == queue.Queued }, "Paused")
+	},
+}
+
+var queueResumeCmd = &cobra.Command{
+	Use:   "resume <id>... | --all",
+	Short: "Queue paused or failed items again",
+	Run: func(cmd *cobra.Command, args []string) {
+		all, _ := cmd.Flags().GetBool("all")
+		resumable := func(it *queue.Item) bool {
+			return it.Status == queue.Paused || it.Status == queue.Failed
+		}
+		updateItems(cmd, args, all, func(_ *queue.File, it *queue.Item) error {
+			if !resumable(it) {
+				return fmt.Errorf("it is %s, not paused or failed", it.Status)
+			}
+			it.Status = queue.Queued
+			it.Error = ""
+			return nil
+		}, resumable, "Queued")
+	},
+}
+
+var queueRunCmd = &cobra.Command{
+	Use:   "run",
+	Short: "Download the queued items until the queue is empty",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		verbose, _ := cmd.Flags().GetBool("verbose")
+		log := logger.New(verbose, "")
+		defer log.Close()
+
+		q, err := openQueue(cmd)
+		if err != nil {
+			log.Error(err.Error())
+			os.Exit(ExitFailure)
+		}
+
+		var opts downloader.Options
+		if err := readHTTP(cmd, &opts, log); err != nil {
+			log.Error(err.Error())
+			os.Exit(ExitFailure)
+		}
+		jobs, err := readRun(cmd, &opts, log)
+		if err != nil {
+			log.Error(err.Error())
+			os.Exit(ExitFailure)
+		}
+		// one client for every job, so the rate limits cap them together
+		if opts.Client, err = httpclient.New(opts.HTTP, log); err != nil {
+			log.Error(err.Error())
+			os.Exit(ExitFailure)
+		}
+
+		log.Info("Running queue " + q.Path)
+		runner := &queue.Runner{Queue: q, Downloader: downloader.New(log), Log: log, Jobs: jobs}
+		sum, err := runner.Run(cmd.Context(), opts)
+		log.Info(fmt.Sprintf("%d done, %d failed, %d interrupted", sum.Done, sum.Failed, sum.Interrupted))
+
+		switch {
+		case err != nil:
+			log.Error(err.Error())
+			os.Exit(ExitFailure)
+		case cmd.Context().Err() != nil:
+			log.Warn("Queue run interrupted, run it again to continue")
+			os.Exit(ExitInterrupted)
+		case sum.Failed > 0:
+			log.Warn("Some items failed, retry them with maya queue resume --all")
+			os.Exit(ExitFailure)
+		}
+		log.Success("Queue drained")
+	},
+}
+
+// openQueue returns the queue named by --queue-file, or the default one
+func openQueue(cmd *cobra.Command) (*queue.Queue, error) {
+	path, _ := cmd.Flags().GetString("queue-file")
+	if path == "" {
+		var err error
+		if path, err = queue.DefaultPath(); err != nil {
+			return nil, fmt.Errorf("can't find the data dir, use --queue-file: %w", err)
+		}
+	}
+	return queue.Open(path), nil
+}
+
+// updateItems applies change to the items named by args, or to every item
+// matching filter when all is set, and reports each outcome with verb
+func updateItems(cmd *cobra.Command, args []string, all bool,
+	change func(f *queue.File, it *queue.Item) error, filter func(it *queue.Item) bool, verb string) {
+	verbose, _ := cmd.Flags().GetBool("verbose")
+	log := logger.New(verbose, "")
+	defer log.Close()
+
+	ids, err := parseIDs(args)
+	if err == nil && len(ids) == 0 && !all {
+		err = errors.New("no item ids given")
+	}
+	if err != nil {
+		log.Error(err.Error())
+		os.Exit(ExitFailure)
+	}
+
+	q, err := openQueue(cmd)
+	if err != nil {
+		log.Error(err.Error())
+		os.Exit(ExitFailure)
+	}
+
+	failed := 0
+	err = q.Update(func(f *queue.File) error {
+		var targets []*queue.Item
+		if all {
+			for _, it := range f.Items {
+				if filter(it) {
+					targets = append(targets, it)
+				}
+			}
+		}
+		for _, id := range ids {
+			it := f.Find(id)
+			if it == nil {
+				log.Warn(fmt.Sprintf("Item %d is not in the queue", id))
+				failed++
+				continue
+			}
+			targets = append(targets, it)
+		}
+
+		for _, it := range targets {
+			if err := change(f, it); err != nil {
+				log.Warn(fmt.Sprintf("Item %d unchanged: %v", it.ID, err))
+				failed++
+				continue
+			}
+			log.Success(fmt.Sprintf("%s item %d: %s", verb, it.ID, it.Label()))
+		}
+		if all && len(targets) == 0 {
+			log.Info("No matching items")
+		}
+		return nil
+	})
+	if err != nil {
+		log.Error(err.Error())
+		os.Exit(ExitFailure)
+	}
+	if failed > 0 {
+		os.Exit(ExitFailure)
+	}
+}
+
+// parseIDs parses item ids given as arguments
+func parseIDs(args []string) ([]int, error) {
+	ids := make([]int, 0, len(args))
+	for _, a := range args {
+		id, err := strconv.Atoi(a)
+		if err != nil || id < 1 {
+			return nil, fmt.Errorf("invalid item id %q", a)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// selectionLabel summarizes the choices that tell queued items apart
+func selectionLabel(it *queue.Item) string {
+	c := it.Choices
+	var parts []string
+	if c.Season > 0 {
+		parts = append(parts, fmt.Sprintf("S%02d", c.Season))
+	}
+	if c.Episodes != "" {
+		parts = append(parts, "E"+c.Episodes)
+	}
+	if c.Quality != "" && c.Quality != "best" {
+		parts = append(parts, c.Quality)
+	}
+	if c.SourceLabel != "" {
+		parts = append(parts, strconv.Quote(c.SourceLabel))
+	}
+	if len(parts) == 0 {
+		return "-"
+	}
+	return strings.Join(parts, " ")
+}
+
+func init() {
+	rootCmd.AddCommand(queueCmd)
+	queueCmd.AddCommand(queueAddCmd, queueListCmd, queueRemoveCmd, queuePauseCmd, queueResumeCmd, queueRunCmd)
+
+	queueCmd.PersistentFlags().String("queue-file", "", "Queue file (default: maya/queue.json in the user data dir)")
+
+	queueAddCmd.Flags().StringP("batch", "b", "", "File with URLs to queue, one per line with optional key=value overrides (- for stdin)")
+	queueAddCmd.Flags().Bool("paused", false, "Add the items paused")
+	addSelectionFlags(queueAddCmd)
+	addMetaFlags(queueAddCmd)
+	addHTTPFlags(queueAddCmd)
+
+	queueListCmd.Flags().String("status", "", "Only show items with this status (queued, paused, running, done, failed)")
+	queueRemoveCmd.Flags().Bool("finished", false, "Remove every done item")
+	queuePauseCmd.Flags().Bool("all", false, "Pause every queued item")
+	queueResumeCmd.Flags().Bool("all", false, "Queue every paused and failed item again")
+
+	addHTTPFlags(queueRunCmd)
+	addRunFlags(queueRunCmd)
+}
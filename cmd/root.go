@@ -1,7 +1,10 @@
 package cmd
 
 import (
+	"context"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/ajaysinghnp/maya-cli/internal/logger"
 	"github.com/spf13/cobra"
@@ -9,6 +12,12 @@ import (
 
 var log *logger.Logger
 
+// Exit statuses for commands that fail on their own terms rather than on bad usage
+const (
+	ExitFailure     = 1
+	ExitInterrupted = 130 // 128 + SIGINT, as shells report it
+)
+
 var rootCmd = &cobra.Command{
 	Use:   "maya",
 	Short: "Maya - A Modular Multimedia CLI Tool",
@@ -23,6 +32,7 @@ With Maya, you can:
 
 Usage Examples:
   maya download <url>           # Download a movie or series from a URL
+  maya detect <url>             # Show which provider handles a URL
   maya other-tool --option xyz   # Run other future tools
 `,
 	Version: Version,
@@ -38,9 +48,18 @@ Usage Examples:
 	},
 }
 
-// Execute runs the root command and all subcommands
+// Execute runs the root command and all subcommands. The first Ctrl-C or
+// SIGTERM cancels the command's context so it can stop cleanly; a second one
+// kills the process.
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	if err := rootCmd.ExecuteContext(ctx); err != nil {
 		PrintBanner()
 		if log != nil {
 			log.Error(err.Error())
@@ -0,0 +1,181 @@
+package artwork
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ajaysinghnp/maya-cli/internal/httpclient"
+	"github.com/ajaysinghnp/maya-cli/internal/logger/iface"
+	"github.com/ajaysinghnp/maya-cli/internal/metadata"
+)
+
+// maxImageSize guards against a misconfigured URL streaming a video
+const maxImageSize = 20 << 20
+
+// imageExts maps detected content types to file extensions
+var imageExts = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+	"image/gif":  ".gif",
+}
+
+// Fetcher saves the artwork Jellyfin and Kodi look for next to the media
+type Fetcher struct {
+	Client *httpclient.Client
+	Log    iface.Logger
+}
+
+// image is one artwork file to write: every name gets the same picture
+type image struct {
+	url   string
+	dir   string
+	names []string // without extension, which follows the detected type
+}
+
+// Download fetches the poster (as poster and folder) and fanart into RootDir
+// and the episode thumbnail next to the episode file. Existing files are
+// kept. It returns the paths written.
+func (f *Fetcher) Download(ctx context.Context, m *metadata.Metadata) ([]string, error) {
+	if m.RootDir == "" {
+		return nil, errors.New("artwork: media paths not built")
+	}
+
+	images := []image{
+		{url: m.Thumbnail, dir: m.RootDir, names: []string{"poster", "folder"}},
+		{url: m.Fanart, dir: m.RootDir, names: []string{"fanart"}},
+	}
+	if m.Type == metadata.Series && m.MediaFile != "" {
+		base := filepath.Base(m.MediaFile)
+		images = append(images, image{
+			url:   m.EpisodeThumbnail,
+			dir:   filepath.Dir(m.MediaFile),
+			names: []string{strings.TrimSuffix(base, filepath.Ext(base)) + "-thumb"},
+		})
+	}
+
+	var written []string
+	var errs []error
+	for _, img := range images {
+		if img.url == "" {
+			continue
+		}
+		paths, err := f.save(ctx, img)
+		written = append(written, paths...)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return written, errors.Join(errs...)
+}
+
+// save downloads img once and writes it under every missing name
+func (f *Fetcher) save(ctx context.Context, img image) ([]string, error) {
+	var missing []string
+	for _, name := range img.names {
+		if existing := findExisting(img.dir, name); existing != "" {
+			f.Log.Debug("Artwork already present: " + existing)
+			continue
+		}
+		missing = append(missing, name)
+	}
+	if len(missing) == 0 {
+		return nil, nil
+	}
+
+	if u, err := url.Parse(img.url); err != nil || !u.IsAbs() {
+		return nil, fmt.Errorf("artwork %s: not an absolute URL: %q", missing[0], img.url)
+	}
+
+	data, ext, err := f.fetch(ctx, img.url)
+	if err != nil {
+		return nil, fmt.Errorf("artwork %s: %w", missing[0], err)
+	}
+
+	if err := os.MkdirAll(img.dir, 0755); err != nil {
+		return nil, err
+	}
+
+	var written []string
+	for _, name := range missing {
+		path := filepath.Join(img.dir, name+ext)
+		if err := writeFile(path, data); err != nil {
+			return written, err
+		}
+		written = append(written, path)
+	}
+	return written, nil
+}
+
+// fetch downloads an image and detects its type from the bytes, since
+// servers often send a generic content type
+func (f *Fetcher) fetch(ctx context.Context, imageURL string) ([]byte, string, error) {
+	resp, err := f.Client.Get(ctx, imageURL, http.Header{
+		"Accept": {"image/avif,image/webp,image/png,image/jpeg,*/*;q=0.8"},
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("GET %s: unexpected HTTP status: %d", imageURL, resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxImageSize+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(data) > maxImageSize {
+		return nil, "", fmt.Errorf("GET %s: image larger than %d MiB", imageURL, maxImageSize>>20)
+	}
+
+	contentType := http.DetectContentType(data)
+	ext, ok := imageExts[contentType]
+	if !ok {
+		return nil, "", fmt.Errorf("GET %s: not an image (%s)", imageURL, contentType)
+	}
+	return data, ext, nil
+}
+
+// findExisting returns the artwork file called name with any image
+// extension in dir, or ""
+func findExisting(dir, name string) string {
+	for _, ext := range imageExts {
+		path := filepath.Join(dir, name+ext)
+		if info, err := os.Stat(path); err == nil && info.Size() > 0 {
+			return path
+		}
+	}
+	return ""
+}
+
+// writeFile writes through a temporary file so an interrupted write never
+// leaves a truncated image that later runs would keep. The name is unique
+// because episodes downloaded in parallel save the same show poster.
+func writeFile(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.part")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
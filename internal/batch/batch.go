@@ -0,0 +1,211 @@
+package batch
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ajaysinghnp/maya-cli/internal/downloader"
+	"github.com/ajaysinghnp/maya-cli/internal/metadata"
+	"github.com/ajaysinghnp/maya-cli/utils"
+)
+
+// Stdin is the file name that reads a URL list from standard input
+const Stdin = "-"
+
+// Entry is one URL to download, with the options its batch line overrides
+type Entry struct {
+	URL    string
+	Source string // "args", or the batch file and line it came from
+
+	overrides []override
+}
+
+// override is one key=value setting from a batch line
+type override struct {
+	key, value string
+}
+
+// Keys lists the settings a batch line can override, in the order they are
+// documented
+var Keys = []string{
+	"output", "quality", "source-label", "source-index", "prefer-lang",
+	"season", "episodes", "episode", "title", "year", "type",
+}
+
+// FromArgs returns an entry without overrides for every URL on the command line
+func FromArgs(urls []string) []Entry {
+	entries := make([]Entry, 0, len(urls))
+	for _, u := range urls {
+		entries = append(entries, Entry{URL: u, Source: "args"})
+	}
+	return entries
+}
+
+// ReadFile reads a URL list from path, or from stdin when path is "-"
+func ReadFile(path string) ([]Entry, error) {
+	if path == Stdin {
+		return Parse(os.Stdin, "stdin")
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return Parse(f, path)
+}
+
+// Parse reads one URL per line. Blank lines and lines starting with # are
+// skipped, and a URL may be followed by key=value overrides such as
+//
+//	https://example.com/movie output=/media/movies source-label="No Ads"
+//
+// Every override is checked here so a typo fails before any download starts.
+func Parse(r io.Reader, name string) ([]Entry, error) {
+	var entries []Entry
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields, err := splitFields(text)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", name, line, err)
+		}
+
+		entry := Entry{URL: fields[0], Source: fmt.Sprintf("%s:%d", name, line)}
+		for _, f := range fields[1:] {
+			if strings.HasPrefix(f, "#") {
+				break // trailing comment
+			}
+			key, value, ok := strings.Cut(f, "=")
+			if !ok {
+				return nil, fmt.Errorf("%s:%d: expected key=value, got %q", name, line, f)
+			}
+			o := override{key: strings.ToLower(key), value: value}
+			if err := o.apply(&downloader.Options{}); err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", name, line, err)
+			}
+			entry.overrides = append(entry.overrides, o)
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	return entries, nil
+}
+
+// Options returns base with the entry's overrides applied
+func (e Entry) Options(base downloader.Options) downloader.Options {
+	opts := base
+	// slices are replaced, never appended to, so base is not shared
+	for _, o := range e.overrides {
+		o.apply(&opts) // validated by Parse
+	}
+	return opts
+}
+
+// apply sets the option named by the override
+func (o override) apply(opts *downloader.Options) error {
+	switch o.key {
+	case "output", "o":
+		opts.Output = o.value
+	case "quality", "q":
+		opts.Select.Quality = o.value
+	case "source-label":
+		opts.Source.Label = o.value
+	case "source-index":
+		n, err := positiveInt(o)
+		if err != nil {
+			return err
+		}
+		opts.Source.Index = n
+	case "prefer-lang":
+		opts.Source.PreferLang = utils.NormalizeSlice(strings.Split(o.value, ","))
+	case "season":
+		n, err := positiveInt(o)
+		if err != nil {
+			return err
+		}
+		opts.Season, opts.Meta.Season = n, n
+	case "episodes":
+		set, err := metadata.ParseEpisodes(o.value)
+		if err != nil {
+			return err
+		}
+		opts.Episodes = set
+	case "episode":
+		n, err := positiveInt(o)
+		if err != nil {
+			return err
+		}
+		opts.Meta.Episode = n
+	case "title":
+		opts.Meta.Title = o.value
+	case "year":
+		n, err := positiveInt(o)
+		if err != nil {
+			return err
+		}
+		opts.Meta.Year = n
+	case "type":
+		opts.Meta.Type = metadata.MediaType(strings.ToLower(o.value))
+	default:
+		return fmt.Errorf("unknown override %q (use %s)", o.key, strings.Join(Keys, ", "))
+	}
+	return nil
+}
+
+func positiveInt(o override) (int, error) {
+	n, err := strconv.Atoi(o.value)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid %s %q (expected a number from 1)", o.key, o.value)
+	}
+	return n, nil
+}
+
+// splitFields splits a line on whitespace, keeping double-quoted text
+// (key="two words") together
+func splitFields(line string) ([]string, error) {
+	var (
+		fields  []string
+		current strings.Builder
+		quoted  bool
+		started bool
+	)
+
+	for _, r := range line {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			started = true
+		case !quoted && (r == ' ' || r == '\t'):
+			if started {
+				fields = append(fields, current.String())
+				current.Reset()
+				started = false
+			}
+		default:
+			current.WriteRune(r)
+			started = true
+		}
+	}
+	if quoted {
+		return nil, errors.New("unterminated quote")
+	}
+	if started {
+		fields = append(fields, current.String())
+	}
+	return fields, nil
+}
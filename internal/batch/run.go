@@ -0,0 +1,162 @@
+package batch
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ajaysinghnp/maya-cli/internal/downloader"
+	"github.com/ajaysinghnp/maya-cli/internal/logger/iface"
+	"github.com/ajaysinghnp/maya-cli/internal/progress"
+)
+
+// Job outcomes shown in the summary
+const (
+	StatusDone        = "done"
+	StatusFailed      = "failed"
+	StatusInterrupted = "interrupted"
+	StatusSkipped     = "skipped" // never started because of Ctrl-C
+)
+
+// Result is the outcome of one entry
+type Result struct {
+	Entry   Entry
+	Status  string
+	Err     error
+	Elapsed time.Duration
+}
+
+// Runner downloads the entries of a batch, Jobs at a time
+type Runner struct {
+	Downloader *downloader.Downloader
+	Log        iface.Logger
+	Jobs       int // URLs downloaded at the same time
+}
+
+// Run downloads every entry with base plus the entry's overrides and returns
+// the results in entry order. A failed entry doesn't stop the others;
+// cancelling ctx stops the running ones and skips the rest.
+func (r *Runner) Run(ctx context.Context, entries []Entry, base downloader.Options) []Result {
+	results := make([]Result, len(entries))
+	for i, e := range entries {
+		results[i] = Result{Entry: e, Status: StatusSkipped}
+	}
+
+	jobs := r.Jobs
+	if jobs < 1 {
+		jobs = 1
+	}
+	if jobs > len(entries) {
+		jobs = len(entries)
+	}
+
+	if jobs > 1 {
+		if base.Progress != nil {
+			base.Progress = progress.NewGroup(base.Progress, fmt.Sprintf("%d downloads", len(entries)))
+		}
+		// parallel jobs can't share the terminal for prompts
+		base.Source.Auto = true
+		base.Meta.Prompt = false
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+
+	for w := 0; w < jobs; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i] = r.download(ctx, i, entries, base)
+			}
+		}()
+	}
+
+feed:
+	for i := range entries {
+		select {
+		case indexes <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	return results
+}
+
+// download runs a single entry
+func (r *Runner) download(ctx context.Context, i int, entries []Entry, base downloader.Options) Result {
+	e := entries[i]
+	if len(entries) > 1 {
+		r.Log.Info(fmt.Sprintf("[%d/%d] Analyzing URL: %s", i+1, len(entries), e.URL))
+	} else {
+		r.Log.Info("Analyzing URL: " + e.URL)
+	}
+
+	started := time.Now()
+	err := r.Downloader.StartDownload(ctx, e.URL, e.Options(base))
+	// a live recording stopped with Ctrl-C returns no error: it is kept and
+	// counts as done
+	res := Result{Entry: e, Status: StatusDone, Err: err, Elapsed: time.Since(started)}
+
+	switch {
+	case err != nil && ctx.Err() != nil:
+		// cut short by Ctrl-C; running the batch again resumes it
+		res.Status = StatusInterrupted
+	case err != nil:
+		res.Status = StatusFailed
+		if len(entries) > 1 {
+			r.Log.Error(fmt.Sprintf("Download failed: %s: %v", e.URL, err))
+		} else {
+			r.Log.Error(fmt.Sprintf("Download failed: %v", err))
+		}
+	case len(entries) > 1:
+		r.Log.Success("Download completed: " + e.URL)
+	}
+	return res
+}
+
+// Count returns how many results have the given status
+func Count(results []Result, status string) int {
+	n := 0
+	for _, res := range results {
+		if res.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// WriteSummary prints one row per entry: where it came from, the URL, the
+// outcome, how long it took and the error, if any
+func WriteSummary(w io.Writer, results []Result) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "#\tSOURCE\tURL\tSTATUS\tTIME\tERROR")
+
+	for i, res := range results {
+		elapsed, msg := "-", ""
+		if res.Elapsed > 0 {
+			elapsed = res.Elapsed.Round(time.Second).String()
+		}
+		if res.Err != nil && res.Status == StatusFailed {
+			msg = firstLine(res.Err.Error())
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\n",
+			i+1, res.Entry.Source, res.Entry.URL, res.Status, elapsed, msg)
+	}
+
+	return tw.Flush()
+}
+
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
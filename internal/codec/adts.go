@@ -0,0 +1,68 @@
+package codec
+
+import (
+	"errors"
+	"fmt"
+)
+
+// sampleRates indexed by the ADTS / AudioSpecificConfig sampling frequency index
+var sampleRates = []int{
+	96000, 88200, 64000, 48000, 44100, 32000,
+	24000, 22050, 16000, 12000, 11025, 8000, 7350,
+}
+
+// ADTSHeader is the fixed + variable header in front of every AAC ADTS frame
+type ADTSHeader struct {
+	Profile         int // audio object type - 1
+	SampleRateIndex int
+	Channels        int
+	HeaderLen       int // 7, or 9 when a CRC is present
+	FrameLen        int // header + raw data
+}
+
+// SampleRate returns the sampling frequency in Hz
+func (h ADTSHeader) SampleRate() int {
+	if h.SampleRateIndex < len(sampleRates) {
+		return sampleRates[h.SampleRateIndex]
+	}
+	return 0
+}
+
+// AudioSpecificConfig builds the two byte MPEG-4 decoder config for the stream
+func (h ADTSHeader) AudioSpecificConfig() []byte {
+	objectType := h.Profile + 1
+	return []byte{
+		byte(objectType<<3) | byte(h.SampleRateIndex>>1),
+		byte(h.SampleRateIndex&1)<<7 | byte(h.Channels<<3),
+	}
+}
+
+// ParseADTSHeader parses the ADTS header at the start of b
+func ParseADTSHeader(b []byte) (ADTSHeader, error) {
+	if len(b) < 7 {
+		return ADTSHeader{}, errors.New("adts: short header")
+	}
+	if b[0] != 0xFF || b[1]&0xF0 != 0xF0 {
+		return ADTSHeader{}, errors.New("adts: missing sync word")
+	}
+
+	h := ADTSHeader{
+		Profile:         int(b[2] >> 6),
+		SampleRateIndex: int(b[2]>>2) & 0x0F,
+		Channels:        int(b[2]&0x01)<<2 | int(b[3]>>6),
+		HeaderLen:       7,
+		FrameLen:        int(b[3]&0x03)<<11 | int(b[4])<<3 | int(b[5]>>5),
+	}
+	if b[1]&0x01 == 0 {
+		h.HeaderLen = 9
+	}
+
+	if h.FrameLen < h.HeaderLen {
+		return ADTSHeader{}, fmt.Errorf("adts: invalid frame length %d", h.FrameLen)
+	}
+	if h.SampleRateIndex >= len(sampleRates) {
+		return ADTSHeader{}, fmt.Errorf("adts: invalid sampling frequency index %d", h.SampleRateIndex)
+	}
+
+	return h, nil
+}
@@ -0,0 +1,77 @@
+package codec
+
+// SplitAnnexB splits an Annex-B byte stream (00 00 01 / 00 00 00 01 start
+// codes) into NAL units. Start codes and trailing zero bytes are dropped.
+func SplitAnnexB(b []byte) [][]byte {
+	var (
+		nals  [][]byte
+		start = -1
+	)
+
+	for i := 0; i+2 < len(b); {
+		if b[i] == 0 && b[i+1] == 0 && b[i+2] == 1 {
+			if start >= 0 {
+				nals = appendNAL(nals, b[start:i])
+			}
+			i += 3
+			start = i
+			continue
+		}
+		i++
+	}
+
+	if start >= 0 {
+		nals = appendNAL(nals, b[start:])
+	}
+
+	return nals
+}
+
+func appendNAL(nals [][]byte, nal []byte) [][]byte {
+	// zeros before a start code belong to it (or are trailing_zero_8bits)
+	end := len(nal)
+	for end > 0 && nal[end-1] == 0 {
+		end--
+	}
+	if end == 0 {
+		return nals
+	}
+	return append(nals, nal[:end])
+}
+
+// JoinAnnexB writes NAL units back as an Annex-B stream using 4-byte start codes
+func JoinAnnexB(nals [][]byte) []byte {
+	size := 0
+	for _, n := range nals {
+		size += 4 + len(n)
+	}
+
+	out := make([]byte, 0, size)
+	for _, n := range nals {
+		out = append(out, 0, 0, 0, 1)
+		out = append(out, n...)
+	}
+	return out
+}
+
+// RemoveEmulationPrevention strips the 0x03 bytes inserted after every
+// 00 00 sequence so the NAL payload can be read as plain RBSP
+func RemoveEmulationPrevention(nal []byte) []byte {
+	out := make([]byte, 0, len(nal))
+	zeros := 0
+
+	for _, c := range nal {
+		if zeros >= 2 && c == 3 {
+			zeros = 0
+			continue
+		}
+		if c == 0 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+		out = append(out, c)
+	}
+
+	return out
+}
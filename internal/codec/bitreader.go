@@ -0,0 +1,59 @@
+package codec
+
+import "errors"
+
+var errShortRBSP = errors.New("codec: unexpected end of parameter set")
+
+// bitReader reads MSB-first bits and Exp-Golomb codes from an RBSP
+type bitReader struct {
+	b   []byte
+	pos int // in bits
+	err error
+}
+
+func (r *bitReader) u(n int) uint32 {
+	var v uint32
+	for i := 0; i < n; i++ {
+		if r.pos >= len(r.b)*8 {
+			r.err = errShortRBSP
+			return 0
+		}
+		bit := r.b[r.pos/8] >> (7 - r.pos%8) & 1
+		v = v<<1 | uint32(bit)
+		r.pos++
+	}
+	return v
+}
+
+func (r *bitReader) flag() bool {
+	return r.u(1) == 1
+}
+
+func (r *bitReader) skip(n int) {
+	r.pos += n
+	if r.pos > len(r.b)*8 {
+		r.err = errShortRBSP
+	}
+}
+
+// ue reads an unsigned Exp-Golomb code
+func (r *bitReader) ue() uint32 {
+	zeros := 0
+	for r.u(1) == 0 {
+		if r.err != nil || zeros > 31 {
+			r.err = errShortRBSP
+			return 0
+		}
+		zeros++
+	}
+	return (1<<zeros - 1) + r.u(zeros)
+}
+
+// se reads a signed Exp-Golomb code
+func (r *bitReader) se() int32 {
+	v := r.ue()
+	if v&1 == 1 {
+		return int32(v+1) / 2
+	}
+	return -int32(v / 2)
+}
@@ -0,0 +1,127 @@
+package codec
+
+import "errors"
+
+// H.264 NAL unit types used when remuxing
+const (
+	H264NALSlice = 1
+	H264NALIDR   = 5
+	H264NALSEI   = 6
+	H264NALSPS   = 7
+	H264NALPPS   = 8
+	H264NALAUD   = 9
+)
+
+// H264SPS holds the sequence parameter set fields needed for an avcC box
+type H264SPS struct {
+	Profile       uint8
+	Compatibility uint8
+	Level         uint8
+	Width         int
+	Height        int
+}
+
+// ParseH264SPS parses an H.264 SPS NAL unit (including its header byte)
+func ParseH264SPS(nal []byte) (*H264SPS, error) {
+	if len(nal) < 4 || nal[0]&0x1F != H264NALSPS {
+		return nil, errors.New("h264: not an SPS NAL unit")
+	}
+
+	rbsp := RemoveEmulationPrevention(nal[1:])
+	r := &bitReader{b: rbsp}
+
+	sps := &H264SPS{
+		Profile:       uint8(r.u(8)),
+		Compatibility: uint8(r.u(8)),
+		Level:         uint8(r.u(8)),
+	}
+	r.ue() // seq_parameter_set_id
+
+	chromaFormat := uint32(1)
+	switch sps.Profile {
+	case 100, 110, 122, 244, 44, 83, 86, 118, 128, 138, 139, 134, 135:
+		chromaFormat = r.ue()
+		if chromaFormat == 3 {
+			r.skip(1) // separate_colour_plane_flag
+		}
+		r.ue()        // bit_depth_luma_minus8
+		r.ue()        // bit_depth_chroma_minus8
+		r.skip(1)     // qpprime_y_zero_transform_bypass_flag
+		if r.flag() { // seq_scaling_matrix_present_flag
+			lists := 8
+			if chromaFormat == 3 {
+				lists = 12
+			}
+			for i := 0; i < lists; i++ {
+				if !r.flag() {
+					continue
+				}
+				size := 16
+				if i >= 6 {
+					size = 64
+				}
+				skipScalingList(r, size)
+			}
+		}
+	}
+
+	r.ue()          // log2_max_frame_num_minus4
+	switch r.ue() { // pic_order_cnt_type
+	case 0:
+		r.ue() // log2_max_pic_order_cnt_lsb_minus4
+	case 1:
+		r.skip(1) // delta_pic_order_always_zero_flag
+		r.se()    // offset_for_non_ref_pic
+		r.se()    // offset_for_top_to_bottom_field
+		n := r.ue()
+		for i := uint32(0); i < n && r.err == nil; i++ {
+			r.se()
+		}
+	}
+
+	r.ue()    // max_num_ref_frames
+	r.skip(1) // gaps_in_frame_num_value_allowed_flag
+
+	widthMbs := int(r.ue()) + 1
+	heightMapUnits := int(r.ue()) + 1
+	frameMbsOnly := r.u(1)
+	if frameMbsOnly == 0 {
+		r.skip(1) // mb_adaptive_frame_field_flag
+	}
+	r.skip(1) // direct_8x8_inference_flag
+
+	var cropLeft, cropRight, cropTop, cropBottom int
+	if r.flag() {
+		cropLeft, cropRight = int(r.ue()), int(r.ue())
+		cropTop, cropBottom = int(r.ue()), int(r.ue())
+	}
+
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	cropUnitX, cropUnitY := 1, int(2-frameMbsOnly)
+	switch chromaFormat {
+	case 1:
+		cropUnitX, cropUnitY = 2, 2*int(2-frameMbsOnly)
+	case 2:
+		cropUnitX = 2
+	}
+
+	sps.Width = widthMbs*16 - (cropLeft+cropRight)*cropUnitX
+	sps.Height = int(2-frameMbsOnly)*heightMapUnits*16 - (cropTop+cropBottom)*cropUnitY
+
+	return sps, nil
+}
+
+func skipScalingList(r *bitReader, size int) {
+	last, next := int32(8), int32(8)
+	for j := 0; j < size && r.err == nil; j++ {
+		if next != 0 {
+			next = (last + r.se() + 256) % 256
+		}
+		if next != 0 {
+			last = next
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package codec
+
+import "errors"
+
+// H.265 NAL unit types used when remuxing
+const (
+	H265NALVPS = 32
+	H265NALSPS = 33
+	H265NALPPS = 34
+	H265NALAUD = 35
+)
+
+// H265NALType returns the NAL unit type of an H.265 NAL unit
+func H265NALType(nal []byte) uint8 {
+	if len(nal) == 0 {
+		return 0
+	}
+	return nal[0] >> 1 & 0x3F
+}
+
+// H265IsKeyframe reports whether the NAL unit is an IRAP picture (BLA, IDR or CRA)
+func H265IsKeyframe(nal []byte) bool {
+	t := H265NALType(nal)
+	return t >= 16 && t <= 21
+}
+
+// H265SPS holds the sequence parameter set fields needed for an hvcC box
+type H265SPS struct {
+	ProfileSpace      uint8
+	Tier              uint8
+	Profile           uint8
+	Compatibility     uint32
+	Constraints       uint64 // 48 bits
+	Level             uint8
+	ChromaFormat      uint8
+	BitDepthLuma      uint8 // minus 8
+	BitDepthChroma    uint8 // minus 8
+	TemporalLayers    uint8
+	TemporalIDNesting bool
+	Width             int
+	Height            int
+}
+
+// ParseH265SPS parses an H.265 SPS NAL unit (including its two header bytes)
+func ParseH265SPS(nal []byte) (*H265SPS, error) {
+	if len(nal) < 16 || H265NALType(nal) != H265NALSPS {
+		return nil, errors.New("h265: not an SPS NAL unit")
+	}
+
+	r := &bitReader{b: RemoveEmulationPrevention(nal[2:])}
+
+	r.skip(4) // sps_video_parameter_set_id
+	maxSubLayers := int(r.u(3))
+	sps := &H265SPS{
+		TemporalLayers:    uint8(maxSubLayers + 1),
+		TemporalIDNesting: r.flag(),
+	}
+
+	// profile_tier_level(1, sps_max_sub_layers_minus1)
+	sps.ProfileSpace = uint8(r.u(2))
+	sps.Tier = uint8(r.u(1))
+	sps.Profile = uint8(r.u(5))
+	sps.Compatibility = r.u(32)
+	sps.Constraints = uint64(r.u(16))<<32 | uint64(r.u(32))
+	sps.Level = uint8(r.u(8))
+
+	profilePresent := make([]bool, maxSubLayers)
+	levelPresent := make([]bool, maxSubLayers)
+	for i := 0; i < maxSubLayers; i++ {
+		profilePresent[i] = r.flag()
+		levelPresent[i] = r.flag()
+	}
+	if maxSubLayers > 0 {
+		r.skip(2 * (8 - maxSubLayers))
+	}
+	for i := 0; i < maxSubLayers; i++ {
+		if profilePresent[i] {
+			r.skip(88)
+		}
+		if levelPresent[i] {
+			r.skip(8)
+		}
+	}
+
+	r.ue() // sps_seq_parameter_set_id
+	sps.ChromaFormat = uint8(r.ue())
+	if sps.ChromaFormat == 3 {
+		r.skip(1) // separate_colour_plane_flag
+	}
+
+	width, height := int(r.ue()), int(r.ue())
+	if r.flag() { // conformance_window_flag
+		subW, subH := 1, 1
+		switch sps.ChromaFormat {
+		case 1:
+			subW, subH = 2, 2
+		case 2:
+			subW = 2
+		}
+		left, right := int(r.ue()), int(r.ue())
+		top, bottom := int(r.ue()), int(r.ue())
+		width -= (left + right) * subW
+		height -= (top + bottom) * subH
+	}
+
+	sps.BitDepthLuma = uint8(r.ue())
+	sps.BitDepthChroma = uint8(r.ue())
+
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	sps.Width, sps.Height = width, height
+	return sps, nil
+}
@@ -1,43 +1,253 @@
 package downloader
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/ajaysinghnp/maya-cli/internal/artwork"
 	"github.com/ajaysinghnp/maya-cli/internal/downloader/m3u8"
-	moviebazar "github.com/ajaysinghnp/maya-cli/internal/downloader/movie-bazar"
+	"github.com/ajaysinghnp/maya-cli/internal/httpclient"
 	"github.com/ajaysinghnp/maya-cli/internal/logger/iface"
-	"github.com/ajaysinghnp/maya-cli/internal/metadata/resolver"
+	"github.com/ajaysinghnp/maya-cli/internal/metadata"
+	"github.com/ajaysinghnp/maya-cli/internal/postprocess"
+	"github.com/ajaysinghnp/maya-cli/internal/progress"
+	"github.com/ajaysinghnp/maya-cli/internal/provider"
+	_ "github.com/ajaysinghnp/maya-cli/internal/provider/all" // built-in providers
 )
 
 type Downloader struct {
 	log iface.Logger
 }
 
+// Options holds the user choices for a single download
+type Options struct {
+	Output     string
+	Resume     bool
+	Concurrent int // segment workers per download
+	Select     m3u8.Selection
+	Source     provider.SourceSelection // which of the listed sources to use
+	Meta       metadata.Manual          // metadata for sources that can't be scraped
+	Metadata   *metadata.Metadata       // already resolved (queued items), skips resolution
+	Live       bool                     // record live playlists until they end
+	Duration   time.Duration            // live recording limit, 0 = no limit
+	HTTP       httpclient.Config        // shared by page, playlist, key and segment requests
+	Client     *httpclient.Client       // built from HTTP when nil; share one so --limit-rate caps every job together
+	Progress   progress.Reporter        // segment progress, nil reports nothing
+
+	// Series pages
+	Season   int                 // season to download, 0 = every season
+	Episodes metadata.EpisodeSet // episodes to download, zero value = all
+	Parallel int                 // episodes downloaded at the same time
+
+	NFO         bool   // write Jellyfin/Kodi .nfo files next to the media
+	Artwork     bool   // save the poster, fanart and episode thumbnail next to the media
+	Container   string // "mp4" (default) or "mkv"
+	PostProcess string // "none" (default), "ffmpeg" or "auto"
+}
+
 func New(log iface.Logger) *Downloader {
 	return &Downloader{log: log}
 }
 
-// StartDownload decides which module to use based on URL
-func (d *Downloader) StartDownload(
-	url string,
-	output string,
-	resume bool,
-	concurrent int,
-) error {
-	// Detect source
-	source := resolver.DetectSource(url)
+// StartDownload looks up the provider for url and downloads the title, or
+// the selected episodes of a series. Cancelling ctx stops every request and
+// worker; finished segments are kept for --resume and ctx.Err() is returned.
+func (d *Downloader) StartDownload(ctx context.Context, url string, opts Options) error {
+	p, env, err := d.detect(ctx, url, opts)
+	if err != nil {
+		return err
+	}
 
 	// 1️⃣ Resolve metadata
-	meta, err := resolver.Resolve(source, url, d.log)
+	meta := opts.Metadata
+	if meta == nil {
+		if meta, err = d.resolve(ctx, p, url, env); err != nil {
+			return err
+		}
+	} else {
+		d.log.Info("Using stored metadata for " + meta.Title)
+	}
+
+	ff, err := d.postProcessor(opts.PostProcess)
 	if err != nil {
 		return err
 	}
 
+	container := strings.ToLower(strings.TrimPrefix(opts.Container, "."))
+	if container == "" {
+		container = "mp4"
+	}
+	if container != "mp4" && ff == nil {
+		return fmt.Errorf("%s output requires --postprocess ffmpeg", container)
+	}
+
+	r := run{provider: p, url: url, env: env, ff: ff, container: container, opts: opts}
+	if meta.Type == metadata.Series && len(meta.Seasons) > 0 {
+		return d.downloadSeries(ctx, r, meta)
+	}
+	return d.downloadTitle(ctx, r, meta)
+}
+
+// Resolve looks up the provider for url and returns its name and the
+// resolved metadata without downloading anything
+func (d *Downloader) Resolve(ctx context.Context, url string, opts Options) (string, *metadata.Metadata, error) {
+	p, env, err := d.detect(ctx, url, opts)
+	if err != nil {
+		return "", nil, err
+	}
+
+	meta, err := d.resolve(ctx, p, url, env)
+	if err != nil {
+		return "", nil, err
+	}
+	return p.Name(), meta, nil
+}
+
+// detect picks the provider for url and builds the environment it runs in
+func (d *Downloader) detect(ctx context.Context, url string, opts Options) (provider.Provider, provider.Env, error) {
+	client := opts.Client
+	if client == nil {
+		var err error
+		if client, err = httpclient.New(opts.HTTP, d.log); err != nil {
+			return nil, provider.Env{}, err
+		}
+	}
+
+	detected, err := provider.Detect(ctx, client, url)
+	if err != nil {
+		d.log.Warn("Unsupported URL format")
+		return nil, provider.Env{}, err
+	}
+	p := detected.Provider
+	d.log.Info(fmt.Sprintf("Using provider: %s (%s)", p.Name(), detected.Reason()))
+
+	env := provider.Env{Client: client, Log: d.log, Manual: opts.Meta, Source: opts.Source}
+	return p, env, nil
+}
+
+func (d *Downloader) resolve(ctx context.Context, p provider.Provider, url string, env provider.Env) (*metadata.Metadata, error) {
+	d.log.Info("Resolving metadata for URL: " + url)
+	meta, err := p.ResolveMetadata(ctx, url, env)
+	if err != nil {
+		return nil, err
+	}
+
 	d.log.Success("Metadata resolved successfully!")
+	return meta, nil
+}
+
+// run is what every title of one StartDownload call shares
+type run struct {
+	provider  provider.Provider
+	url       string
+	env       provider.Env
+	ff        *postprocess.FFmpeg
+	container string
+	opts      Options
+}
+
+// downloadSeries downloads the selected episodes of a series page, opts.Parallel at a time
+func (d *Downloader) downloadSeries(ctx context.Context, r run, show *metadata.Metadata) error {
+	opts := r.opts
+	if opts.Live {
+		return errors.New("--live cannot be used with series pages")
+	}
+
+	episodes, err := show.SelectEpisodes(opts.Season, opts.Episodes)
+	if err != nil {
+		return err
+	}
+
+	parallel := opts.Parallel
+	if parallel < 1 {
+		parallel = 1
+	}
+	if parallel > len(episodes) {
+		parallel = len(episodes)
+	}
+	d.log.Info(fmt.Sprintf("Downloading %d episode(s) of %s, %d at a time", len(episodes), show.Title, parallel))
+
+	// pick the source once, by label, so parallel episodes never prompt
+	if len(episodes[0].Sources) > 0 {
+		i, err := provider.ChooseSource(episodes[0].Sources, r.env.Source, d.log)
+		if err != nil {
+			return err
+		}
+		label := episodes[0].Sources[i].Label
+		d.log.Success("Using source " + label + " for every episode")
+		r.env.Source = provider.SourceSelection{Label: label, PreferLang: r.env.Source.PreferLang, Auto: true}
+	}
+
+	if parallel > 1 && opts.Progress != nil {
+		r.opts.Progress = progress.NewGroup(opts.Progress, fmt.Sprintf("%s (%d episodes)", show.Title, len(episodes)))
+	}
+
+	jobs := make(chan *metadata.Metadata)
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		failed []string
+	)
+
+	for w := 0; w < parallel; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ep := range jobs {
+				name := fmt.Sprintf("S%02dE%02d", ep.Season, ep.Episode)
+				if err := d.downloadTitle(ctx, r, ep); err != nil {
+					if ctx.Err() != nil {
+						continue
+					}
+					d.log.Error(fmt.Sprintf("Episode %s failed: %v", name, err))
+					mu.Lock()
+					failed = append(failed, name)
+					mu.Unlock()
+					continue
+				}
+				d.log.Success("Episode " + name + " done")
+			}
+		}()
+	}
+
+	queued := 0
+feed:
+	for _, ep := range episodes {
+		select {
+		case jobs <- ep:
+			queued++
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		d.log.Warn(fmt.Sprintf("Interrupted, %d of %d episodes were not started", len(episodes)-queued, len(episodes)))
+		return ctx.Err()
+	}
+
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		return fmt.Errorf("%d of %d episodes failed: %s", len(failed), len(episodes), strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// downloadTitle downloads a single movie or episode and writes its NFO,
+// artwork and sidecar
+func (d *Downloader) downloadTitle(ctx context.Context, r run, meta *metadata.Metadata) error {
+	opts, ff := r.opts, r.ff
 
 	// 2️⃣ Build paths (single source of truth)
-	meta.BuildPaths(output, "mp4", d.log)
+	meta.BuildPaths(opts.Output, r.container, d.log)
 	d.log.Info("Paths prepared for download.")
 
 	// 3️⃣ Prepare temp path
@@ -46,35 +256,179 @@ func (d *Downloader) StartDownload(
 	d.log.Debug("Temporary file path: " + tempFile)
 	d.log.Info("Final output file: " + meta.MediaFile)
 
-	// 4️⃣ Dispatch by source type
-	switch source {
-	case resolver.SourceM3U8:
-		d.log.Info("Detected direct M3U8 link.")
-		return m3u8.Download(m3u8.Options{
-			URL:        url,
-			Output:     meta.MediaFile,
-			TempDir:    tempDir,
-			Resume:     resume,
-			Concurrent: concurrent,
-			Log:        d.log,
-		})
+	// ffmpeg does the container work, so download into a plain MPEG-TS file
+	output := meta.MediaFile
+	if ff != nil {
+		output = strings.TrimSuffix(output, filepath.Ext(output)) + ".ts"
+	}
+
+	// 4️⃣ Resolve the streams and download the first one that works
+	streams, err := r.provider.ResolveStreams(ctx, r.url, meta, r.env)
+	if err != nil {
+		return err
+	}
+	if len(streams) == 0 {
+		return errors.New("no playable streams found")
+	}
 
-	case resolver.SourceMoviesBazar:
-		d.log.Info("Initiating MoviesBazar download...")
-		return moviebazar.HandleMovie(moviebazar.Options{
-			Meta:       meta,
+	started := time.Now()
+	var (
+		result *m3u8.Result
+		chosen provider.Stream
+	)
+	for i, stream := range streams {
+		if i > 0 {
+			d.log.Warn(fmt.Sprintf("Trying stream %d of %d: %s", i+1, len(streams), stream.URL))
+		}
+		result, err = r.provider.Download(ctx, provider.Job{
+			Stream:     stream,
+			Output:     output,
 			TempDir:    tempDir,
-			Resume:     resume,
-			Concurrent: concurrent,
-			Log:        d.log,
+			Resume:     opts.Resume,
+			Concurrent: opts.Concurrent,
+			Select:     opts.Select,
+			Live:       opts.Live,
+			Duration:   opts.Duration,
+			Progress:   opts.Progress,
+		}, r.env)
+		if err == nil {
+			chosen = stream
+			break
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		if i < len(streams)-1 {
+			d.log.Warn(fmt.Sprintf("Stream failed: %v", err))
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	// a live recording stopped with Ctrl-C is kept, so finish it like any other
+	if opts.Live {
+		ctx = context.WithoutCancel(ctx)
+	}
+
+	if opts.NFO {
+		d.writeNFOs(meta)
+	}
+	if opts.Artwork {
+		d.downloadArtwork(ctx, r.env.Client, meta)
+	}
+
+	// 5️⃣ Mux everything into the final container
+	if ff != nil {
+		err = ff.Run(ctx, postprocess.Job{
+			Input:     result.Output,
+			Output:    meta.MediaFile,
+			Audio:     postProcessTracks(result.Audio),
+			Subtitles: postProcessTracks(result.Subtitles),
+			Meta:      meta,
 		})
+		if err != nil {
+			return err
+		}
+	}
 
-	case resolver.SourceYouTube:
-		d.log.Info("Detected YouTube URL")
-		return errors.New("YouTube downloader not implemented yet")
+	// 6️⃣ Leave a record of the download for later commands
+	d.recordDownload(meta, result, chosen.Source, started)
+	return nil
+}
+
+// recordDownload writes the maya.json sidecar; the media is complete at
+// this point, so a failure is only reported
+func (d *Downloader) recordDownload(meta *metadata.Metadata, result *m3u8.Result, source *metadata.Source, started time.Time) {
+	rec := metadata.SidecarRecord{
+		Source:   source,
+		Stream:   metadata.StreamInfo{URL: result.URL},
+		Started:  started,
+		Finished: time.Now(),
+	}
+	if v := result.Variant; v != nil {
+		rec.Stream.VariantURL = v.URI
+		rec.Stream.Bandwidth = v.Bandwidth
+		rec.Stream.Resolution = v.Resolution()
+		rec.Stream.Codecs = v.Codecs
+	}
+
+	if err := metadata.RecordDownload(meta, rec); err != nil {
+		d.log.Warn("Failed to write " + metadata.SidecarName + ": " + err.Error())
+		return
+	}
+	d.log.Debug("Recorded download in " + filepath.Join(meta.RootDir, metadata.SidecarName))
+}
+
+// writeNFOs writes the NFO files; the media is already safe on disk, so a
+// failure only costs the library metadata and is not fatal
+func (d *Downloader) writeNFOs(meta *metadata.Metadata) {
+	paths, err := metadata.WriteNFOs(meta)
+	for _, p := range paths {
+		d.log.Debug("Wrote " + p)
+	}
+	if err != nil {
+		d.log.Warn("Failed to write NFO files: " + err.Error())
+		return
+	}
+	d.log.Success(fmt.Sprintf("Wrote %d NFO file(s)", len(paths)))
+}
+
+// downloadArtwork saves the images, which like the NFOs are not worth
+// failing a finished download over
+func (d *Downloader) downloadArtwork(ctx context.Context, client *httpclient.Client, meta *metadata.Metadata) {
+	fetcher := &artwork.Fetcher{Client: client, Log: d.log}
+	paths, err := fetcher.Download(ctx, meta)
+	for _, p := range paths {
+		d.log.Debug("Saved " + p)
+	}
+	if err != nil {
+		d.log.Warn("Failed to save artwork: " + err.Error())
+	}
+	if len(paths) > 0 {
+		d.log.Success(fmt.Sprintf("Saved %d artwork file(s)", len(paths)))
+	}
+}
+
+// postProcessor returns the ffmpeg stage for the --postprocess mode, or nil
+// when downloads are used as-is
+func (d *Downloader) postProcessor(mode string) (*postprocess.FFmpeg, error) {
+	switch strings.ToLower(mode) {
+	case "", "none":
+		return nil, nil
+
+	case "ffmpeg":
+		ff, err := postprocess.FindFFmpeg(d.log)
+		if err != nil {
+			return nil, fmt.Errorf("--postprocess ffmpeg: %w", err)
+		}
+		d.log.Info("Post-processing with " + ff.Path)
+		return ff, nil
+
+	case "auto":
+		ff, err := postprocess.FindFFmpeg(d.log)
+		if err != nil {
+			d.log.Info("ffmpeg not found, skipping post-processing")
+			return nil, nil
+		}
+		d.log.Info("Post-processing with " + ff.Path)
+		return ff, nil
 
 	default:
-		d.log.Warn("Unsupported URL format")
-		return errors.New("unsupported URL format")
+		return nil, fmt.Errorf("unknown post-processor %q (use none, ffmpeg or auto)", mode)
+	}
+}
+
+func postProcessTracks(tracks []m3u8.Track) []postprocess.Track {
+	var out []postprocess.Track
+	for _, t := range tracks {
+		out = append(out, postprocess.Track{
+			Path:     t.Path,
+			Language: t.Language,
+			Title:    t.Name,
+			Default:  t.Default,
+			Forced:   t.Forced,
+		})
 	}
+	return out
 }
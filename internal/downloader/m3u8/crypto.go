@@ -0,0 +1,146 @@
+package m3u8
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// keyFetch is one key request, shared by every segment waiting for its URI
+type keyFetch struct {
+	done chan struct{} // closed once key and err are set
+	key  []byte
+	err  error
+}
+
+// key returns the content key behind uri, fetching it once per download.
+// Only the map is guarded by keysMu, so segments using other keys don't wait
+// for this request. A failed fetch isn't kept and the next segment retries.
+func (s *session) key(uri string) ([]byte, error) {
+	s.keysMu.Lock()
+	f, ok := s.keys[uri]
+	if !ok {
+		f = &keyFetch{done: make(chan struct{})}
+		s.keys[uri] = f
+	}
+	s.keysMu.Unlock()
+
+	if ok {
+		<-f.done
+		return f.key, f.err
+	}
+
+	f.key, f.err = s.fetchKey(uri)
+	if f.err != nil {
+		s.keysMu.Lock()
+		delete(s.keys, uri)
+		s.keysMu.Unlock()
+	}
+	close(f.done)
+	return f.key, f.err
+}
+
+// fetchKey downloads a 16 byte AES key
+func (s *session) fetchKey(uri string) ([]byte, error) {
+	resp, err := s.get(uri, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch key: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch key, status: %d", resp.StatusCode)
+	}
+
+	k, err := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key: %w", err)
+	}
+	if len(k) != 16 {
+		return nil, fmt.Errorf("invalid key length %d, expected 16 bytes", len(k))
+	}
+
+	s.log.Debug("Fetched decryption key: " + uri)
+	return k, nil
+}
+
+// segmentIV returns the explicit IV or the one derived from the media sequence
+func segmentIV(k *Key, sequence int) []byte {
+	if k.IV != nil {
+		return k.IV
+	}
+	iv := make([]byte, 16)
+	binary.BigEndian.PutUint64(iv[8:], uint64(sequence))
+	return iv
+}
+
+// checkKey rejects keys maya cannot use before any segment is downloaded
+func checkKey(k *Key) error {
+	if k == nil {
+		return nil
+	}
+	if f := strings.ToLower(k.KeyFormat); f != "" && f != "identity" {
+		return fmt.Errorf("unsupported key format %q (DRM protected stream)", k.KeyFormat)
+	}
+	switch k.Method {
+	case "AES-128", "SAMPLE-AES":
+		return nil
+	default:
+		return fmt.Errorf("unsupported encryption method %q", k.Method)
+	}
+}
+
+// decrypt returns the clear bytes of an encrypted segment
+func (s *session) decrypt(seg Segment, data []byte) ([]byte, error) {
+	k := seg.Key
+	if k == nil {
+		return data, nil
+	}
+
+	key, err := s.key(k.URI)
+	if err != nil {
+		return nil, err
+	}
+	iv := segmentIV(k, seg.Sequence)
+
+	switch k.Method {
+	case "AES-128":
+		return decryptAES128(data, key, iv)
+	case "SAMPLE-AES":
+		return decryptSampleAES(data, key, iv)
+	default:
+		return nil, fmt.Errorf("unsupported encryption method %q", k.Method)
+	}
+}
+
+// decryptAES128 decrypts a whole AES-128-CBC segment and strips PKCS#7 padding
+func decryptAES128(data, key, iv []byte) ([]byte, error) {
+	if len(data) == 0 || len(data)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("encrypted segment size %d is not a multiple of the block size", len(data))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(out, data)
+
+	pad := int(out[len(out)-1])
+	if pad == 0 || pad > aes.BlockSize || pad > len(out) {
+		return nil, errors.New("invalid PKCS#7 padding, wrong key?")
+	}
+	for _, b := range out[len(out)-pad:] {
+		if int(b) != pad {
+			return nil, errors.New("invalid PKCS#7 padding, wrong key?")
+		}
+	}
+
+	return out[:len(out)-pad], nil
+}
@@ -0,0 +1,188 @@
+package m3u8
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// CBC-AES128 vectors from NIST SP 800-38A, F.2.1
+var (
+	nistKey    = unhex("2b7e151628aed2a6abf7158809cf4f3c")
+	nistIV     = unhex("000102030405060708090a0b0c0d0e0f")
+	nistPlain  = unhex("6bc1bee22e409f96e93d7e117393172a ae2d8a571e03ac9c9eb76fac45af8e51 30c81c46a35ce411e5fbc1191a0a52ef f69f2445df4f9b17ad2b417be66c3710")
+	nistCipher = unhex("7649abac8119b246cee98e9b12e9197d 5086cb9b507219ee95db113a917678b2 73bed6b8e3c1743b7116e69e22229516 3ff1caa1681fac09120eca307586e1a7")
+)
+
+func unhex(s string) []byte {
+	b, err := hex.DecodeString(strings.ReplaceAll(s, " ", ""))
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func TestSegmentIV(t *testing.T) {
+	if iv := segmentIV(&Key{IV: nistIV}, 7); !bytes.Equal(iv, nistIV) {
+		t.Errorf("explicit IV = %x, want %x", iv, nistIV)
+	}
+
+	want := unhex("0000000000000000 0102030405060708")
+	if iv := segmentIV(&Key{}, 0x0102030405060708); !bytes.Equal(iv, want) {
+		t.Errorf("sequence IV = %x, want %x", iv, want)
+	}
+}
+
+func TestDecryptAES128(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   []byte
+		iv     []byte
+		want   []byte
+		errMsg string
+	}{
+		{
+			// the NIST blocks followed by a whole block of PKCS#7 padding
+			name: "explicit IV",
+			data: append(append([]byte(nil), nistCipher...), unhex("8cb82807230e1321d3fae00d18cc2012")...),
+			iv:   nistIV,
+			want: nistPlain,
+		},
+		{
+			// 29 bytes padded with three 0x03, encrypted with openssl
+			name: "IV from the media sequence",
+			data: unhex("af771fde6fe774d51378f4c4e3995cd5 cdd833fd594d93ba73659cc8a6e0dd81"),
+			iv:   segmentIV(&Key{}, 7),
+			want: []byte("segment with media sequence 7"),
+		},
+		{
+			// the last NIST plaintext block ends in 0x10 but isn't all padding
+			name:   "bad padding bytes",
+			data:   nistCipher,
+			iv:     nistIV,
+			errMsg: "invalid PKCS#7 padding",
+		},
+		{
+			// the third NIST plaintext block ends in 0xef
+			name:   "padding longer than a block",
+			data:   nistCipher[:48],
+			iv:     nistIV,
+			errMsg: "invalid PKCS#7 padding",
+		},
+		{
+			name:   "partial block",
+			data:   nistCipher[:40],
+			iv:     nistIV,
+			errMsg: "not a multiple of the block size",
+		},
+		{
+			name:   "empty",
+			iv:     nistIV,
+			errMsg: "not a multiple of the block size",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decryptAES128(tt.data, nistKey, tt.iv)
+			if tt.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+					t.Fatalf("error = %v, want %q", err, tt.errMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("got %x, want %x", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSessionKey(t *testing.T) {
+	var hits, misses atomic.Int32
+	release := make(chan struct{})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/slow.key", func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		<-release
+		w.Write(nistKey)
+	})
+	mux.HandleFunc("/fast.key", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(nistKey)
+	})
+	mux.HandleFunc("/missing.key", func(w http.ResponseWriter, r *http.Request) {
+		misses.Add(1)
+		http.NotFound(w, r)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	s := newSession(context.Background(), Options{Log: nopLogger{}})
+	seg := Segment{Sequence: 7, Key: &Key{Method: "AES-128", URI: srv.URL + "/slow.key"}}
+	data := unhex("af771fde6fe774d51378f4c4e3995cd5 cdd833fd594d93ba73659cc8a6e0dd81")
+
+	// segments waiting for the slow key share one request
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			got, err := s.decrypt(seg, data)
+			if err != nil || string(got) != "segment with media sequence 7" {
+				t.Errorf("decrypt = %q, %v", got, err)
+			}
+		}()
+	}
+
+	// and don't hold up segments with other keys
+	for hits.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	fast := make(chan error, 1)
+	go func() {
+		_, err := s.key(srv.URL + "/fast.key")
+		fast <- err
+	}()
+	select {
+	case err := <-fast:
+		if err != nil {
+			t.Errorf("fast key: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("fast key waited for the slow one")
+	}
+	close(release)
+	wg.Wait()
+
+	if n := hits.Load(); n != 1 {
+		t.Errorf("slow key fetched %d times, want 1", n)
+	}
+
+	// failures aren't kept, the next segment asks again
+	for i := 0; i < 2; i++ {
+		if _, err := s.key(srv.URL + "/missing.key"); err == nil {
+			t.Fatal("missing key: no error")
+		}
+	}
+	if n := misses.Load(); n != 2 {
+		t.Errorf("missing key fetched %d times, want 2", n)
+	}
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Info(string)    {}
+func (nopLogger) Debug(string)   {}
+func (nopLogger) Warn(string)    {}
+func (nopLogger) Error(string)   {}
+func (nopLogger) Success(string) {}
@@ -0,0 +1,57 @@
+package m3u8
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// initSections returns the distinct EXT-X-MAP init sections in playlist order
+func initSections(segments []Segment) []*Map {
+	var maps []*Map
+	seen := map[*Map]bool{}
+	for _, seg := range segments {
+		if seg.Map != nil && !seen[seg.Map] {
+			seen[seg.Map] = true
+			maps = append(maps, seg.Map)
+		}
+	}
+	return maps
+}
+
+// initPath returns where the i-th init section is stored inside the work directory
+func initPath(workDir string, i int) string {
+	return filepath.Join(workDir, fmt.Sprintf("init-%02d.seg", i))
+}
+
+// downloadInitSections fetches every init section once and returns the file
+// holding each of them. They are small, so they are fetched again on resume.
+func (s *session) downloadInitSections(segments []Segment, workDir string) (map[*Map]string, error) {
+	paths := map[*Map]string{}
+
+	for i, m := range initSections(segments) {
+		// an init section without explicit IV is decrypted with the sequence
+		// number of the first segment that uses it
+		seq := 0
+		for _, seg := range segments {
+			if seg.Map == m {
+				seq = seg.Sequence
+				break
+			}
+		}
+
+		init := Segment{
+			URI:       m.URI,
+			Sequence:  seq,
+			ByteRange: m.ByteRange,
+			Key:       m.Key,
+		}
+
+		path := initPath(workDir, i)
+		if _, _, err := s.downloadSegment(init, path); err != nil {
+			return nil, fmt.Errorf("init section %s: %w", m.URI, err)
+		}
+		paths[m] = path
+	}
+
+	return paths, nil
+}
@@ -0,0 +1,173 @@
+package m3u8
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// maxRefreshFailures is how many playlist refreshes in a row may fail before
+// a live recording is stopped
+const maxRefreshFailures = 5
+
+// recordLive keeps polling a live or event playlist and downloads new segments
+// as they appear, until EXT-X-ENDLIST, the duration limit or the session
+// context is cancelled (Ctrl-C). Whatever was recorded up to that point is
+// assembled into a playable output.
+func (s *session) recordLive(playlist *MediaPlaylist, base *url.URL, workDir, output, playlistURL, variantID string) error {
+	log := s.log
+	s.live = true
+	stop := s.ctx.Done()
+
+	// older segments have left the playlist window, so a recording can't be resumed
+	if err := os.RemoveAll(workDir); err != nil {
+		return fmt.Errorf("failed to clear temp dir: %w", err)
+	}
+	if err := os.MkdirAll(workDir, 0755); err != nil {
+		return fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	s.manifest = newManifest(workDir)
+	s.manifest.reset(playlistURL, variantID)
+
+	limit := s.opts.Duration.Seconds()
+	if limit > 0 {
+		log.Info("Recording live stream for up to " + s.opts.Duration.String() + ", press Ctrl-C to stop early")
+	} else {
+		log.Info("Recording live stream until it ends, press Ctrl-C to stop")
+	}
+
+	var (
+		recorded   []Segment
+		lastSeq    = -1
+		queued     float64 // seconds of media handed to the downloader
+		failures   int
+		refreshURL = base.String()
+		// every refresh parses new *Map values, keep one per init section
+		maps = map[string]*Map{}
+	)
+
+	s.progress.Start(filepath.Base(output), 0, 0)
+	defer s.progress.Finish()
+
+record:
+	for {
+		var fresh []Segment
+		for _, seg := range playlist.Segments {
+			if seg.Sequence <= lastSeq {
+				continue
+			}
+			if limit > 0 && queued >= limit {
+				break
+			}
+			if err := checkSegment(seg); err != nil {
+				return err
+			}
+			if seg.Map != nil {
+				id := seg.Map.URI
+				if br := seg.Map.ByteRange; br != nil {
+					id += fmt.Sprintf("@%d-%d", br.Offset, br.Length)
+				}
+				if m, ok := maps[id]; ok {
+					seg.Map = m
+				} else {
+					maps[id] = seg.Map
+				}
+			}
+			fresh = append(fresh, seg)
+			queued += seg.Duration
+		}
+
+		if len(fresh) > 0 {
+			if lastSeq >= 0 && fresh[0].Sequence > lastSeq+1 {
+				log.Warn(fmt.Sprintf("Missed %d segments that left the playlist before they were fetched",
+					fresh[0].Sequence-lastSeq-1))
+			}
+			lastSeq = fresh[len(fresh)-1].Sequence
+			s.progress.Grow(len(fresh))
+
+			if err := s.downloadSegments(fresh, workDir, s.opts.Concurrent); err != nil && s.ctx.Err() == nil {
+				log.Warn("Some segments failed to download: " + err.Error())
+			}
+			for _, seg := range fresh {
+				if s.manifest.completed(seg, segmentPath(workDir, seg)) {
+					recorded = append(recorded, seg)
+				}
+			}
+
+			log.Info(fmt.Sprintf("Recorded %d segments (%s)", len(recorded), segmentsDuration(recorded)))
+		}
+
+		if playlist.EndList {
+			log.Info("Stream ended (EXT-X-ENDLIST)")
+			break
+		}
+		if limit > 0 && queued >= limit {
+			log.Info("Reached the recording duration limit")
+			break
+		}
+
+		// poll every target duration, twice as often while nothing changes
+		wait := time.Duration(playlist.TargetDuration) * time.Second
+		if len(fresh) == 0 {
+			wait /= 2
+		}
+		if wait < time.Second {
+			wait = time.Second
+		}
+
+		select {
+		case <-stop:
+			log.Warn("Interrupted, finishing the recording")
+			break record
+		case <-time.After(wait):
+		}
+
+		data, newBase, err := s.fetchPlaylist(refreshURL)
+		if err == nil {
+			var pl *MediaPlaylist
+			if pl, err = parseMediaPlaylist(data, newBase); err == nil {
+				playlist, refreshURL, failures = pl, newBase.String(), 0
+				continue
+			}
+		}
+
+		failures++
+		if failures >= maxRefreshFailures {
+			log.Warn(fmt.Sprintf("Playlist refresh failed %d times in a row, stopping: %v", failures, err))
+			break
+		}
+		log.Warn("Playlist refresh failed, retrying: " + err.Error())
+	}
+
+	s.progress.Finish()
+
+	// a stopped recording is still assembled, which may need init sections
+	s.ctx = context.WithoutCancel(s.ctx)
+
+	if len(recorded) == 0 {
+		return errors.New("no segments were recorded")
+	}
+
+	log.Success(fmt.Sprintf("Recording finished: %d segments, %s", len(recorded), segmentsDuration(recorded)))
+
+	if err := s.assemble(recorded, workDir, output); err != nil {
+		return err
+	}
+
+	cleanupTemp(workDir, s.opts.TempDir, log)
+
+	return nil
+}
+
+// segmentsDuration returns the total duration of segments, rounded to seconds
+func segmentsDuration(segments []Segment) time.Duration {
+	var total float64
+	for _, seg := range segments {
+		total += seg.Duration
+	}
+	return time.Duration(total * float64(time.Second)).Round(time.Second)
+}
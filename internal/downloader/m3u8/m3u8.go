@@ -1,44 +1,311 @@
 package m3u8
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
-	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ajaysinghnp/maya-cli/internal/logger/iface"
+	"github.com/ajaysinghnp/maya-cli/internal/mpegts"
+	"github.com/ajaysinghnp/maya-cli/internal/remux"
 )
 
-func Download(opts Options) error {
+// Download fetches the playlist at opts.URL into opts.Output and returns the
+// files it produced. Cancelling ctx stops the download and leaves the finished
+// segments in the temp dir for --resume; a live recording is assembled from
+// what was recorded instead.
+func Download(ctx context.Context, opts Options) (*Result, error) {
 	log := opts.Log
 	log.Info("Starting M3U8 download: " + opts.URL)
 	log.Debug("Output file: " + opts.Output)
 	log.Info(fmt.Sprintf("Temp dir: %s | Resume: %v | Concurrency: %d", opts.TempDir, opts.Resume, opts.Concurrent))
 
+	s := newSession(ctx, opts)
+
 	log.Info("Requesting M3U8 playlist...")
-	resp, err := http.Get(opts.URL)
+	playlistData, base, err := s.fetchPlaylist(opts.URL)
 	if err != nil {
-		return fmt.Errorf("failed to fetch playlist: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	log.Success("M3U8 playlist requested successfully!")
+	log.Success("M3U8 playlist fetched successfully!")
+	log.Debug("Playlist data length: " + fmt.Sprint(len(playlistData)))
+
+	// identifies the chosen rendition in the resume manifest
+	variantID := ""
+
+	var (
+		master  *MasterPlaylist
+		variant *Variant
+	)
 
-	if resp.StatusCode != 200 {
-		return fmt.Errorf("failed to fetch playlist, status: %d", resp.StatusCode)
+	if isMasterPlaylist(playlistData) {
+		master, err = parseMasterPlaylist(playlistData, base)
+		if err != nil {
+			return nil, err
+		}
+
+		log.Info(fmt.Sprintf("Master playlist with %d variants:", len(master.Variants)))
+		for i, v := range master.Variants {
+			log.Info(fmt.Sprintf("  [%d] %s", i+1, v))
+		}
+
+		variant, err = selectVariant(master.Variants, opts.Select)
+		if err != nil {
+			return nil, err
+		}
+		log.Success("Selected variant: " + variant.String())
+		variantID = variant.String()
+		s.result.Variant = variant
+
+		log.Info("Requesting variant playlist...")
+		playlistData, base, err = s.fetchPlaylist(variant.URI)
+		if err != nil {
+			return nil, err
+		}
+		log.Success("Variant playlist fetched successfully!")
 	}
 
-	log.Success("M3U8 playlist fetched successfully!")
+	if err := s.downloadMedia(opts.URL, playlistData, base, opts.Output, variantID); err != nil {
+		return nil, err
+	}
 
-	playlistData, err := io.ReadAll(resp.Body)
+	if len(opts.Select.Audio) == 0 && len(opts.Select.Subtitles) == 0 {
+		return s.result, nil
+	}
+
+	if master == nil {
+		log.Warn("Not a master playlist, no alternate audio or subtitle tracks available")
+		return s.result, nil
+	}
+
+	if s.live {
+		log.Warn("Alternate audio and subtitle tracks are not recorded for live streams")
+		return s.result, nil
+	}
+
+	if err := s.downloadRenditions(master, variant); err != nil {
+		return nil, err
+	}
+	return s.result, nil
+}
+
+// downloadMedia runs the segment pipeline for one media playlist: parse,
+// resume, download, merge into output and clean up
+func (s *session) downloadMedia(playlistURL string, playlistData []byte, base *url.URL, output, variantID string) error {
+	log := s.log
+
+	playlist, err := parseMediaPlaylist(playlistData, base)
 	if err != nil {
-		return fmt.Errorf("failed to read playlist: %w", err)
+		return err
 	}
 
-	log.Debug("Playlist data length: " + fmt.Sprint(len(playlistData)))
+	if len(playlist.Segments) == 0 {
+		return errors.New("playlist contains no segments")
+	}
+
+	live := !playlist.EndList && s.opts.Live
+	if !playlist.EndList && !live {
+		log.Warn("Playlist has no EXT-X-ENDLIST, downloading the segments currently listed (use --live to record it)")
+	}
+
+	encrypted, method := 0, ""
+	for _, seg := range playlist.Segments {
+		if err := checkSegment(seg); err != nil {
+			return err
+		}
+		if seg.Key != nil {
+			encrypted++
+			method = seg.Key.Method
+		}
+	}
+	if encrypted > 0 {
+		log.Info(fmt.Sprintf("Stream is encrypted (%s), %d segments will be decrypted", method, encrypted))
+	}
+
+	log.Info(fmt.Sprintf(
+		"Playlist parsed: %d segments, duration %s",
+		len(playlist.Segments),
+		time.Duration(playlist.Duration()*float64(time.Second)).Round(time.Second),
+	))
+
+	workDir := filepath.Join(s.opts.TempDir, workDirName(output))
+	if !s.opts.Resume {
+		if err := os.RemoveAll(workDir); err != nil {
+			return fmt.Errorf("failed to clear temp dir: %w", err)
+		}
+	}
+	if err := os.MkdirAll(workDir, 0755); err != nil {
+		return fmt.Errorf("failed to create temp dir: %w", err)
+	}
+
+	if live {
+		return s.recordLive(playlist, base, workDir, output, playlistURL, variantID)
+	}
+
+	pending, err := s.prepareResume(playlist.Segments, workDir, playlistURL, variantID)
+	if err != nil {
+		return err
+	}
+
+	if done := len(playlist.Segments) - len(pending); done > 0 {
+		log.Info(fmt.Sprintf("Resuming: %d/%d segments already downloaded", done, len(playlist.Segments)))
+	}
+
+	log.Info("Downloading segments...")
+	s.progress.Start(filepath.Base(output), len(playlist.Segments), len(playlist.Segments)-len(pending))
+	err = s.downloadSegments(pending, workDir, s.opts.Concurrent)
+	s.progress.Finish()
+	if err != nil {
+		if s.ctx.Err() != nil {
+			if err := s.manifest.save(); err != nil {
+				log.Warn("Failed to write resume manifest: " + err.Error())
+			}
+			log.Warn(fmt.Sprintf("Interrupted, %d finished segments kept in %s for --resume", s.manifest.done(), workDir))
+		}
+		return err
+	}
+	log.Success("All segments downloaded")
+
+	if err := s.assemble(playlist.Segments, workDir, output); err != nil {
+		return err
+	}
+
+	cleanupTemp(workDir, s.opts.TempDir, log)
+
+	return nil
+}
+
+// assemble merges the downloaded segments into output, prepending fMP4 init
+// sections and remuxing MPEG-TS into MP4 when the output asks for it
+func (s *session) assemble(segments []Segment, workDir, output string) error {
+	log := s.log
+
+	var inits map[*Map]string
+	if maps := initSections(segments); len(maps) > 0 {
+		log.Info(fmt.Sprintf("Fragmented MP4 stream, downloading %d init section(s)", len(maps)))
+		if len(maps) > 1 {
+			log.Warn("Init section changes mid-stream, some players may not handle the merged file")
+		}
+		var err error
+		if inits, err = s.downloadInitSections(segments, workDir); err != nil {
+			return err
+		}
+	}
+
+	// concatenated MPEG-TS segments need a real MP4 container for .mp4 outputs,
+	// fMP4 segments behind their init section already are one
+	toMP4 := strings.EqualFold(filepath.Ext(output), ".mp4") &&
+		isTransportStream(segmentPath(workDir, segments[0]))
+
+	merged := output
+	if toMP4 {
+		merged = filepath.Join(workDir, "merged.ts")
+	}
+
+	log.Info("Merging segments into " + merged)
+	if err := mergeSegments(segments, workDir, merged, inits); err != nil {
+		return err
+	}
+	log.Success("Segments merged successfully!")
+
+	if toMP4 {
+		log.Info("Remuxing MPEG-TS into MP4...")
+		if err := os.MkdirAll(filepath.Dir(output), 0755); err != nil {
+			return err
+		}
+		if err := remux.TSToMP4(merged, output, log); err != nil {
+			return fmt.Errorf("failed to remux into MP4: %w", err)
+		}
+		log.Success("Remuxed into " + output)
+	}
 
-	// TODO:
-	// 1. Fetch playlist
-	// 2. Parse segments
-	// 3. Download segments concurrently
-	// 4. Merge segments into opts.Output
-	// 5. Handle resume logic via tempDir
 	return nil
 }
+
+// prepareResume loads the resume manifest and returns the segments that still
+// need downloading. Segments whose file is missing, truncated or fails its
+// checksum are downloaded again.
+func (s *session) prepareResume(segments []Segment, workDir, playlistURL, variantID string) ([]Segment, error) {
+	man, err := loadManifest(workDir)
+	if err != nil {
+		s.log.Warn("Ignoring unreadable resume manifest: " + err.Error())
+		man = newManifest(workDir)
+	}
+
+	if !man.matches(playlistURL, variantID) {
+		if len(man.Segments) > 0 {
+			s.log.Warn("Resume data belongs to a different stream, starting over")
+		}
+		man.reset(playlistURL, variantID)
+	}
+
+	var pending []Segment
+	for _, seg := range segments {
+		if man.completed(seg, segmentPath(workDir, seg)) {
+			continue
+		}
+		man.markPending(seg)
+		pending = append(pending, seg)
+	}
+
+	if err := man.save(); err != nil {
+		return nil, fmt.Errorf("failed to write resume manifest: %w", err)
+	}
+
+	s.manifest = man
+	return pending, nil
+}
+
+// cleanupTemp removes the work directory of a finished download and the temp
+// dir itself once no other download is using it
+func cleanupTemp(workDir, tempDir string, log iface.Logger) {
+	if err := os.RemoveAll(workDir); err != nil {
+		log.Warn("Failed to remove temp files: " + err.Error())
+		return
+	}
+	if err := os.Remove(tempDir); err == nil {
+		log.Debug("Removed temp dir: " + tempDir)
+	}
+	log.Debug("Removed temp files: " + workDir)
+}
+
+// checkSegment rejects segments maya cannot download or decrypt
+func checkSegment(seg Segment) error {
+	if err := checkKey(seg.Key); err != nil {
+		return err
+	}
+	if seg.Map != nil && seg.Key != nil && seg.Key.Method == "SAMPLE-AES" {
+		return errors.New("SAMPLE-AES encrypted fMP4 (CMAF cbcs) streams are not supported")
+	}
+	return nil
+}
+
+// isTransportStream reports whether a downloaded segment holds MPEG-TS packets
+func isTransportStream(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	buf := make([]byte, mpegts.PacketSize+1)
+	n, _ := io.ReadFull(f, buf)
+	if n == 0 || buf[0] != mpegts.SyncByte {
+		return false
+	}
+	return n <= mpegts.PacketSize || buf[mpegts.PacketSize] == mpegts.SyncByte
+}
+
+// workDirName derives a per-output folder name so parallel downloads sharing
+// the same temp dir don't clash
+func workDirName(output string) string {
+	base := filepath.Base(output)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
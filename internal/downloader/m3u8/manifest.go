@@ -0,0 +1,186 @@
+package m3u8
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const manifestName = "manifest.json"
+
+// Segment states recorded in the resume manifest
+const (
+	statePending = "pending"
+	stateDone    = "done"
+)
+
+// segmentRecord is the resume state of one downloaded segment
+type segmentRecord struct {
+	Size     int64  `json:"size"`
+	Checksum string `json:"sha256"`
+	State    string `json:"state"`
+}
+
+// manifest tracks which segments of a download are complete so an
+// interrupted download can continue where it stopped
+type manifest struct {
+	PlaylistURL string                 `json:"playlistUrl"`
+	Variant     string                 `json:"variant,omitempty"`
+	Segments    map[int]*segmentRecord `json:"segments"`
+
+	path string
+	mu   sync.Mutex
+}
+
+// newManifest returns an empty manifest stored in workDir
+func newManifest(workDir string) *manifest {
+	return &manifest{
+		Segments: map[int]*segmentRecord{},
+		path:     filepath.Join(workDir, manifestName),
+	}
+}
+
+// loadManifest reads the manifest in workDir; a missing file yields an empty manifest
+func loadManifest(workDir string) (*manifest, error) {
+	m := newManifest(workDir)
+
+	data, err := os.ReadFile(m.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, err
+	}
+	if m.Segments == nil {
+		m.Segments = map[int]*segmentRecord{}
+	}
+
+	return m, nil
+}
+
+// matches reports whether the manifest belongs to the same stream. Query
+// strings are ignored because signed playlist URLs change between runs.
+func (m *manifest) matches(playlistURL, variant string) bool {
+	return stripQuery(m.PlaylistURL) == stripQuery(playlistURL) && m.Variant == variant
+}
+
+// reset forgets all segment state and binds the manifest to a new stream
+func (m *manifest) reset(playlistURL, variant string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.PlaylistURL = playlistURL
+	m.Variant = variant
+	m.Segments = map[int]*segmentRecord{}
+}
+
+// completed reports whether seg was fully downloaded and its file is intact
+func (m *manifest) completed(seg Segment, path string) bool {
+	m.mu.Lock()
+	rec := m.Segments[seg.Sequence]
+	m.mu.Unlock()
+
+	if rec == nil || rec.State != stateDone {
+		return false
+	}
+
+	size, sum, err := fileChecksum(path)
+	if err != nil {
+		return false
+	}
+
+	return size == rec.Size && sum == rec.Checksum
+}
+
+// markPending records that seg still has to be downloaded
+func (m *manifest) markPending(seg Segment) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.Segments[seg.Sequence] = &segmentRecord{State: statePending}
+}
+
+// markDone records a finished segment and persists the manifest
+func (m *manifest) markDone(seg Segment, size int64, checksum string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.Segments[seg.Sequence] = &segmentRecord{
+		Size:     size,
+		Checksum: checksum,
+		State:    stateDone,
+	}
+
+	return m.saveLocked()
+}
+
+// done returns how many segments are recorded as finished
+func (m *manifest) done() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n := 0
+	for _, rec := range m.Segments {
+		if rec.State == stateDone {
+			n++
+		}
+	}
+	return n
+}
+
+// save persists the manifest
+func (m *manifest) save() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.saveLocked()
+}
+
+func (m *manifest) saveLocked() error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := m.path + ".part"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, m.path)
+}
+
+// fileChecksum returns the size and hex encoded SHA-256 of a file
+func fileChecksum(path string) (int64, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return 0, "", err
+	}
+
+	return n, hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func stripQuery(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	u.RawQuery, u.Fragment = "", ""
+	return u.String()
+}
@@ -0,0 +1,494 @@
+package m3u8
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ByteRange describes an EXT-X-BYTERANGE sub-range of a resource
+type ByteRange struct {
+	Length int64
+	Offset int64
+}
+
+// Key describes the EXT-X-KEY in effect for a segment
+type Key struct {
+	Method    string // AES-128 or SAMPLE-AES
+	URI       string // absolute key URL
+	IV        []byte // nil when it must be derived from the media sequence
+	KeyFormat string
+}
+
+// Map describes the EXT-X-MAP media initialization section of fMP4 segments
+type Map struct {
+	URI       string // absolute init section URL
+	ByteRange *ByteRange
+	Key       *Key // AES-128 key in effect when the map was declared, nil otherwise
+}
+
+// Segment is a single media segment of a media playlist
+type Segment struct {
+	URI           string // absolute segment URL
+	Duration      float64
+	Title         string
+	Sequence      int // media sequence number
+	ByteRange     *ByteRange
+	Discontinuity bool
+	Key           *Key // nil for clear segments
+	Map           *Map // init section for fMP4 segments, nil for MPEG-TS
+}
+
+// MediaPlaylist holds the parsed contents of an HLS media playlist
+type MediaPlaylist struct {
+	TargetDuration int
+	MediaSequence  int
+	Segments       []Segment
+	EndList        bool
+}
+
+// Duration returns the total duration of all segments in seconds
+func (p *MediaPlaylist) Duration() float64 {
+	var total float64
+	for _, s := range p.Segments {
+		total += s.Duration
+	}
+	return total
+}
+
+// Variant is one rendition advertised by EXT-X-STREAM-INF in a master playlist
+type Variant struct {
+	URI              string // absolute media playlist URL
+	Bandwidth        int64  // peak bits per second
+	AverageBandwidth int64
+	Width            int
+	Height           int
+	Codecs           string
+	FrameRate        float64
+	Audio            string // EXT-X-MEDIA audio group id
+	Subtitles        string // EXT-X-MEDIA subtitles group id
+}
+
+// Resolution returns "WxH" or an empty string when unknown
+func (v Variant) Resolution() string {
+	if v.Width == 0 || v.Height == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%dx%d", v.Width, v.Height)
+}
+
+// String gives a short human readable description of the variant
+func (v Variant) String() string {
+	parts := []string{}
+	if v.Height > 0 {
+		parts = append(parts, fmt.Sprintf("%dp (%s)", v.Height, v.Resolution()))
+	}
+	parts = append(parts, fmt.Sprintf("%.0f kbps", float64(v.Bandwidth)/1000))
+	if v.Codecs != "" {
+		parts = append(parts, v.Codecs)
+	}
+	return strings.Join(parts, " | ")
+}
+
+// Rendition is an alternate audio, subtitle or video track from EXT-X-MEDIA
+type Rendition struct {
+	Type       string // AUDIO, SUBTITLES, VIDEO or CLOSED-CAPTIONS
+	GroupID    string
+	Language   string
+	Name       string
+	URI        string // absolute media playlist URL, empty when muxed into the variant
+	Default    bool
+	Autoselect bool
+	Forced     bool
+}
+
+// MasterPlaylist holds the variants and alternate renditions of an HLS master playlist
+type MasterPlaylist struct {
+	Variants   []Variant
+	Renditions []Rendition
+}
+
+// parseMediaPlaylist parses an HLS media playlist, resolving segment URIs against base
+func parseMediaPlaylist(data []byte, base *url.URL) (*MediaPlaylist, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	pl := &MediaPlaylist{}
+
+	var (
+		header        bool
+		seq           int
+		seqSet        bool
+		duration      float64
+		title         string
+		byteRange     *ByteRange
+		discontinuity bool
+		key           *Key
+		initMap       *Map
+		// next implicit byte-range offset, keyed by segment URI
+		rangeEnd = map[string]int64{}
+	)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		if !header {
+			if !strings.HasPrefix(line, "#EXTM3U") {
+				return nil, errors.New("invalid playlist: missing #EXTM3U header")
+			}
+			header = true
+			continue
+		}
+
+		if !strings.HasPrefix(line, "#") {
+			uri, err := resolveURI(base, line)
+			if err != nil {
+				return nil, fmt.Errorf("invalid segment URI %q: %w", line, err)
+			}
+
+			if !seqSet {
+				seq = pl.MediaSequence
+				seqSet = true
+			}
+
+			if byteRange != nil {
+				if byteRange.Offset < 0 {
+					byteRange.Offset = rangeEnd[uri]
+				}
+				rangeEnd[uri] = byteRange.Offset + byteRange.Length
+			}
+
+			pl.Segments = append(pl.Segments, Segment{
+				URI:           uri,
+				Duration:      duration,
+				Title:         title,
+				Sequence:      seq,
+				ByteRange:     byteRange,
+				Discontinuity: discontinuity,
+				Key:           key,
+				Map:           initMap,
+			})
+
+			seq++
+			duration, title, byteRange, discontinuity = 0, "", nil, false
+			continue
+		}
+
+		tag, value, _ := strings.Cut(line, ":")
+
+		switch tag {
+		case "#EXT-X-TARGETDURATION":
+			pl.TargetDuration, _ = strconv.Atoi(value)
+
+		case "#EXT-X-MEDIA-SEQUENCE":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid EXT-X-MEDIA-SEQUENCE %q", value)
+			}
+			pl.MediaSequence = n
+
+		case "#EXTINF":
+			durStr, t, _ := strings.Cut(value, ",")
+			d, err := strconv.ParseFloat(strings.TrimSpace(durStr), 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid EXTINF duration %q", durStr)
+			}
+			duration, title = d, strings.TrimSpace(t)
+
+		case "#EXT-X-BYTERANGE":
+			br, err := parseByteRange(value)
+			if err != nil {
+				return nil, err
+			}
+			byteRange = br
+
+		case "#EXT-X-DISCONTINUITY":
+			discontinuity = true
+
+		case "#EXT-X-KEY":
+			k, err := parseKey(value, base)
+			if err != nil {
+				return nil, err
+			}
+			key = k
+
+		case "#EXT-X-MAP":
+			m, err := parseMap(value, base, key)
+			if err != nil {
+				return nil, err
+			}
+			initMap = m
+
+		case "#EXT-X-ENDLIST":
+			pl.EndList = true
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read playlist: %w", err)
+	}
+
+	if !header {
+		return nil, errors.New("invalid playlist: empty body")
+	}
+
+	return pl, nil
+}
+
+// isMasterPlaylist reports whether the playlist lists variants instead of segments
+func isMasterPlaylist(data []byte) bool {
+	return bytes.Contains(data, []byte("#EXT-X-STREAM-INF"))
+}
+
+// parseMasterPlaylist parses the variant streams of an HLS master playlist
+func parseMasterPlaylist(data []byte, base *url.URL) (*MasterPlaylist, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	pl := &MasterPlaylist{}
+
+	var (
+		header  bool
+		pending *Variant
+	)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		if !header {
+			if !strings.HasPrefix(line, "#EXTM3U") {
+				return nil, errors.New("invalid playlist: missing #EXTM3U header")
+			}
+			header = true
+			continue
+		}
+
+		if !strings.HasPrefix(line, "#") {
+			if pending == nil {
+				continue
+			}
+			uri, err := resolveURI(base, line)
+			if err != nil {
+				return nil, fmt.Errorf("invalid variant URI %q: %w", line, err)
+			}
+			pending.URI = uri
+			pl.Variants = append(pl.Variants, *pending)
+			pending = nil
+			continue
+		}
+
+		tag, value, _ := strings.Cut(line, ":")
+		if tag == "#EXT-X-MEDIA" {
+			r, err := parseRendition(value, base)
+			if err != nil {
+				return nil, err
+			}
+			pl.Renditions = append(pl.Renditions, *r)
+			continue
+		}
+		if tag != "#EXT-X-STREAM-INF" {
+			continue
+		}
+
+		attrs := parseAttributes(value)
+		v := &Variant{
+			Codecs:    attrs["CODECS"],
+			Audio:     attrs["AUDIO"],
+			Subtitles: attrs["SUBTITLES"],
+		}
+		v.Bandwidth, _ = strconv.ParseInt(attrs["BANDWIDTH"], 10, 64)
+		v.AverageBandwidth, _ = strconv.ParseInt(attrs["AVERAGE-BANDWIDTH"], 10, 64)
+		v.FrameRate, _ = strconv.ParseFloat(attrs["FRAME-RATE"], 64)
+		if w, h, ok := strings.Cut(attrs["RESOLUTION"], "x"); ok {
+			v.Width, _ = strconv.Atoi(w)
+			v.Height, _ = strconv.Atoi(h)
+		}
+		pending = v
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read playlist: %w", err)
+	}
+
+	if len(pl.Variants) == 0 {
+		return nil, errors.New("master playlist contains no variants")
+	}
+
+	return pl, nil
+}
+
+// parseRendition parses an EXT-X-MEDIA attribute list
+func parseRendition(value string, base *url.URL) (*Rendition, error) {
+	attrs := parseAttributes(value)
+
+	r := &Rendition{
+		Type:       strings.ToUpper(attrs["TYPE"]),
+		GroupID:    attrs["GROUP-ID"],
+		Language:   attrs["LANGUAGE"],
+		Name:       attrs["NAME"],
+		Default:    strings.EqualFold(attrs["DEFAULT"], "YES"),
+		Autoselect: strings.EqualFold(attrs["AUTOSELECT"], "YES"),
+		Forced:     strings.EqualFold(attrs["FORCED"], "YES"),
+	}
+
+	if uri := attrs["URI"]; uri != "" {
+		abs, err := resolveURI(base, uri)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rendition URI %q: %w", uri, err)
+		}
+		r.URI = abs
+	}
+
+	return r, nil
+}
+
+// parseKey parses an EXT-X-KEY attribute list; METHOD=NONE yields nil
+func parseKey(value string, base *url.URL) (*Key, error) {
+	attrs := parseAttributes(value)
+
+	k := &Key{
+		Method:    strings.ToUpper(attrs["METHOD"]),
+		KeyFormat: attrs["KEYFORMAT"],
+	}
+
+	switch k.Method {
+	case "NONE":
+		return nil, nil
+	case "":
+		return nil, errors.New("EXT-X-KEY without METHOD")
+	}
+
+	if attrs["URI"] == "" {
+		return nil, fmt.Errorf("EXT-X-KEY with METHOD=%s has no URI", k.Method)
+	}
+
+	uri, err := resolveURI(base, attrs["URI"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid key URI %q: %w", attrs["URI"], err)
+	}
+	k.URI = uri
+
+	if iv := attrs["IV"]; iv != "" {
+		h := strings.TrimPrefix(strings.TrimPrefix(iv, "0x"), "0X")
+		if len(h)%2 == 1 {
+			h = "0" + h
+		}
+		b, err := hex.DecodeString(h)
+		if err != nil || len(b) > 16 {
+			return nil, fmt.Errorf("invalid key IV %q", iv)
+		}
+		k.IV = make([]byte, 16)
+		copy(k.IV[16-len(b):], b)
+	}
+
+	return k, nil
+}
+
+// parseAttributes parses an HLS attribute list (KEY=VALUE,KEY="quoted,value")
+func parseAttributes(s string) map[string]string {
+	attrs := map[string]string{}
+
+	for s != "" {
+		key, rest, ok := strings.Cut(s, "=")
+		if !ok {
+			break
+		}
+		key = strings.TrimSpace(key)
+
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				value, rest = rest[1:], ""
+			} else {
+				value, rest = rest[1:end+1], rest[end+2:]
+			}
+			_, rest, _ = strings.Cut(rest, ",")
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+		}
+
+		attrs[key] = strings.TrimSpace(value)
+		s = rest
+	}
+
+	return attrs
+}
+
+// parseMap parses an EXT-X-MAP attribute list. Only a whole-segment AES-128
+// key applies to the init section, SAMPLE-AES leaves it in the clear.
+func parseMap(value string, base *url.URL, key *Key) (*Map, error) {
+	attrs := parseAttributes(value)
+
+	if attrs["URI"] == "" {
+		return nil, errors.New("EXT-X-MAP without URI")
+	}
+
+	uri, err := resolveURI(base, attrs["URI"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid map URI %q: %w", attrs["URI"], err)
+	}
+
+	m := &Map{URI: uri}
+
+	if v := attrs["BYTERANGE"]; v != "" {
+		br, err := parseByteRange(v)
+		if err != nil {
+			return nil, err
+		}
+		// unlike EXT-X-BYTERANGE, a map range without offset starts at 0
+		if br.Offset < 0 {
+			br.Offset = 0
+		}
+		m.ByteRange = br
+	}
+
+	if key != nil && key.Method == "AES-128" {
+		m.Key = key
+	}
+
+	return m, nil
+}
+
+// parseByteRange parses "<length>[@<offset>]"; a missing offset is reported as -1
+func parseByteRange(value string) (*ByteRange, error) {
+	lenStr, offStr, hasOffset := strings.Cut(value, "@")
+
+	length, err := strconv.ParseInt(strings.TrimSpace(lenStr), 10, 64)
+	if err != nil || length <= 0 {
+		return nil, fmt.Errorf("invalid byte range %q", value)
+	}
+
+	br := &ByteRange{Length: length, Offset: -1}
+	if hasOffset {
+		br.Offset, err = strconv.ParseInt(strings.TrimSpace(offStr), 10, 64)
+		if err != nil || br.Offset < 0 {
+			return nil, fmt.Errorf("invalid byte range %q", value)
+		}
+	}
+
+	return br, nil
+}
+
+// resolveURI resolves a possibly relative playlist URI against the playlist URL
+func resolveURI(base *url.URL, ref string) (string, error) {
+	u, err := url.Parse(ref)
+	if err != nil {
+		return "", err
+	}
+	if base == nil {
+		return u.String(), nil
+	}
+	return base.ResolveReference(u).String(), nil
+}
@@ -0,0 +1,241 @@
+package m3u8
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/ajaysinghnp/maya-cli/utils"
+)
+
+// downloadRenditions saves the requested alternate audio and subtitle tracks
+// of the chosen variant next to the video file
+func (s *session) downloadRenditions(master *MasterPlaylist, variant *Variant) error {
+	sel := s.opts.Select
+	saved := map[string]bool{}
+
+	if len(sel.Audio) > 0 {
+		tracks := pickRenditions(master.Renditions, "AUDIO", variant.Audio, sel.Audio)
+		if len(tracks) == 0 {
+			s.log.Warn(fmt.Sprintf("No audio track matches %s (available: %s)",
+				strings.Join(sel.Audio, ","), availableLanguages(master.Renditions, "AUDIO", variant.Audio)))
+		}
+
+		for _, r := range tracks {
+			if r.URI == "" {
+				s.log.Info(fmt.Sprintf("Audio track %q is muxed into the video, nothing to download", r.Name))
+				continue
+			}
+			if err := s.downloadAudio(r, saved); err != nil {
+				return fmt.Errorf("audio track %q: %w", r.Name, err)
+			}
+		}
+	}
+
+	if len(sel.Subtitles) > 0 {
+		tracks := pickRenditions(master.Renditions, "SUBTITLES", variant.Subtitles, sel.Subtitles)
+		if len(tracks) == 0 {
+			s.log.Warn(fmt.Sprintf("No subtitle track matches %s (available: %s)",
+				strings.Join(sel.Subtitles, ","), availableLanguages(master.Renditions, "SUBTITLES", variant.Subtitles)))
+		}
+
+		for _, r := range tracks {
+			if r.URI == "" {
+				continue
+			}
+			if err := s.downloadSubtitles(r, saved); err != nil {
+				return fmt.Errorf("subtitle track %q: %w", r.Name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// downloadAudio saves an alternate audio rendition as "<video name>.<lang>.<ext>"
+func (s *session) downloadAudio(r Rendition, saved map[string]bool) error {
+	s.log.Info(fmt.Sprintf("Downloading audio track: %s (%s)", r.Name, r.Language))
+
+	data, base, err := s.fetchPlaylist(r.URI)
+	if err != nil {
+		return err
+	}
+
+	output := sidecarPath(s.opts.Output, r, segmentExt(data, base))
+	if saved[output] {
+		s.log.Warn("Skipping duplicate audio track for " + filepath.Base(output))
+		return nil
+	}
+	saved[output] = true
+
+	if err := s.downloadMedia(r.URI, data, base, output, r.GroupID+"/"+r.Name); err != nil {
+		return err
+	}
+
+	s.result.Audio = append(s.result.Audio, renditionTrack(r, output))
+	s.log.Success("Audio track saved: " + output)
+	return nil
+}
+
+// downloadSubtitles saves a WebVTT subtitle rendition as "<video name>.<lang>.srt"
+func (s *session) downloadSubtitles(r Rendition, saved map[string]bool) error {
+	s.log.Info(fmt.Sprintf("Downloading subtitles: %s (%s)", r.Name, r.Language))
+
+	output := sidecarPath(s.opts.Output, r, ".srt")
+	if saved[output] {
+		s.log.Warn("Skipping duplicate subtitle track for " + filepath.Base(output))
+		return nil
+	}
+	saved[output] = true
+
+	data, base, err := s.fetchPlaylist(r.URI)
+	if err != nil {
+		return err
+	}
+
+	// a few servers point straight at a single .vtt file instead of a playlist
+	vtt := data
+	if pl, err := parseMediaPlaylist(data, base); err == nil && len(pl.Segments) > 0 && pl.Segments[0].Map != nil {
+		return errors.New("fragmented MP4 (wvtt) subtitles are not supported")
+	}
+	if !strings.HasPrefix(strings.TrimPrefix(string(data), "\ufeff"), "WEBVTT") {
+		vttPath := filepath.Join(s.opts.TempDir, strings.TrimSuffix(filepath.Base(output), ".srt")+".vtt")
+		if err := s.downloadMedia(r.URI, data, base, vttPath, r.GroupID+"/"+r.Name); err != nil {
+			return err
+		}
+
+		vtt, err = os.ReadFile(vttPath)
+		if err != nil {
+			return err
+		}
+		os.Remove(vttPath)
+		os.Remove(s.opts.TempDir)
+	}
+
+	srt, err := vttToSRT(vtt)
+	if err != nil {
+		return err
+	}
+	if len(srt) == 0 {
+		return errors.New("subtitle track contains no cues")
+	}
+
+	if err := os.WriteFile(output, srt, 0644); err != nil {
+		return err
+	}
+
+	s.result.Subtitles = append(s.result.Subtitles, renditionTrack(r, output))
+	s.log.Success("Subtitles saved: " + output)
+	return nil
+}
+
+// pickRenditions returns the renditions of the given type and group that
+// match the wanted languages, in the order the languages were requested
+func pickRenditions(renditions []Rendition, typ, group string, wanted []string) []Rendition {
+	var candidates []Rendition
+	for _, r := range renditions {
+		if r.Type == typ && (group == "" || r.GroupID == group) {
+			candidates = append(candidates, r)
+		}
+	}
+
+	for _, w := range wanted {
+		if strings.EqualFold(strings.TrimSpace(w), "all") {
+			return candidates
+		}
+	}
+
+	var picked []Rendition
+	seen := map[string]bool{}
+	for _, w := range wanted {
+		for _, r := range candidates {
+			if seen[r.URI] {
+				continue
+			}
+			if utils.SameLanguage(r.Language, w) || utils.SameLanguage(r.Name, w) {
+				picked = append(picked, r)
+				seen[r.URI] = true
+			}
+		}
+	}
+
+	return picked
+}
+
+// availableLanguages lists the languages offered for a rendition type, for error messages
+func availableLanguages(renditions []Rendition, typ, group string) string {
+	var langs []string
+	for _, r := range renditions {
+		if r.Type == typ && (group == "" || r.GroupID == group) {
+			langs = append(langs, fmt.Sprintf("%s [%s]", r.Name, r.Language))
+		}
+	}
+	if len(langs) == 0 {
+		return "none"
+	}
+	return strings.Join(langs, ", ")
+}
+
+// renditionTrack describes a saved rendition for the caller
+func renditionTrack(r Rendition, path string) Track {
+	return Track{
+		Path:     path,
+		Language: renditionLanguage(r),
+		Name:     r.Name,
+		Default:  r.Default,
+		Forced:   r.Forced,
+	}
+}
+
+// renditionLanguage returns the ISO 639-2 code of a rendition, "und" when unknown
+func renditionLanguage(r Rendition) string {
+	lang := utils.LanguageCode(r.Language)
+	if lang == "" {
+		lang = utils.LanguageCode(r.Name)
+	}
+	if lang == "" {
+		lang = "und"
+	}
+	return strings.ReplaceAll(lang, " ", "_")
+}
+
+// sidecarPath builds the Jellyfin external track name for a rendition,
+// e.g. "Movie (2020).eng.srt" or "Movie (2020).eng.forced.srt"
+func sidecarPath(videoPath string, r Rendition, ext string) string {
+	lang := renditionLanguage(r)
+	if r.Forced {
+		lang += ".forced"
+	}
+
+	base := strings.TrimSuffix(videoPath, filepath.Ext(videoPath))
+	return base + "." + lang + ext
+}
+
+// segmentExt guesses the container of a media playlist from its first segment
+func segmentExt(data []byte, base *url.URL) string {
+	pl, err := parseMediaPlaylist(data, base)
+	if err != nil || len(pl.Segments) == 0 {
+		return ".ts"
+	}
+
+	// fMP4 audio behind an EXT-X-MAP init section
+	if pl.Segments[0].Map != nil {
+		return ".m4a"
+	}
+
+	u, err := url.Parse(pl.Segments[0].URI)
+	if err != nil {
+		return ".ts"
+	}
+
+	switch ext := strings.ToLower(path.Ext(u.Path)); ext {
+	case ".aac", ".ac3", ".ec3", ".mp3", ".m4a", ".ts":
+		return ext
+	default:
+		return ".ts"
+	}
+}
@@ -0,0 +1,210 @@
+package m3u8
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/ajaysinghnp/maya-cli/internal/codec"
+	"github.com/ajaysinghnp/maya-cli/internal/mpegts"
+)
+
+// clearStreamTypes maps SAMPLE-AES stream types to their unencrypted equivalents
+var clearStreamTypes = map[uint8]uint8{
+	mpegts.StreamTypeH264SampleAES: mpegts.StreamTypeH264,
+	mpegts.StreamTypeAACSampleAES:  mpegts.StreamTypeAAC,
+}
+
+// decryptSampleAES decrypts a SAMPLE-AES protected MPEG-TS segment.
+//
+// Only the elementary stream samples are encrypted, so every PES packet of an
+// encrypted stream is decrypted and re-packetized while all other packets are
+// copied as they are. The PMT is rewritten to announce clear stream types.
+func decryptSampleAES(data, key, iv []byte) ([]byte, error) {
+	if len(data) == 0 || data[0] != mpegts.SyncByte {
+		return nil, errors.New("SAMPLE-AES is only supported for MPEG-TS segments")
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	type pending struct {
+		adaptation []byte
+		data       []byte
+	}
+
+	var (
+		out         bytes.Buffer
+		pmtPIDs     = map[uint16]bool{}
+		streamTypes = map[uint16]uint8{}
+		pes         = map[uint16]*pending{}
+		cc          = map[uint16]uint8{}
+	)
+
+	flush := func(pid uint16) error {
+		p := pes[pid]
+		if p == nil {
+			return nil
+		}
+		delete(pes, pid)
+
+		packet, err := mpegts.ParsePES(p.data)
+		if err != nil {
+			return err
+		}
+
+		switch streamTypes[pid] {
+		case mpegts.StreamTypeH264SampleAES:
+			packet.Payload = decryptH264Samples(block, iv, packet.Payload)
+		case mpegts.StreamTypeAACSampleAES:
+			if err := decryptAACSamples(block, iv, packet.Payload); err != nil {
+				return err
+			}
+		}
+
+		counter := cc[pid]
+		b, err := mpegts.Packetize(pid, &counter, packet.Marshal(), p.adaptation)
+		if err != nil {
+			return err
+		}
+		cc[pid] = counter
+		out.Write(b)
+		return nil
+	}
+
+	for off := 0; off+mpegts.PacketSize <= len(data); off += mpegts.PacketSize {
+		raw := data[off : off+mpegts.PacketSize]
+
+		pkt, err := mpegts.ParsePacket(raw)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case pkt.PID == 0 && pkt.PayloadStart:
+			pids, err := mpegts.ParsePAT(pkt.Payload)
+			if err != nil {
+				return nil, err
+			}
+			for _, pid := range pids {
+				pmtPIDs[pid] = true
+			}
+			out.Write(raw)
+
+		case pmtPIDs[pkt.PID] && pkt.PayloadStart:
+			pmt, err := mpegts.ParsePMT(pkt.Payload)
+			if err != nil {
+				return nil, err
+			}
+			for _, es := range pmt.Streams {
+				switch es.Type {
+				case mpegts.StreamTypeH264SampleAES, mpegts.StreamTypeAACSampleAES:
+				case 0xC1, 0xC2: // AC-3 / E-AC-3 SAMPLE-AES
+					return nil, fmt.Errorf("SAMPLE-AES stream type 0x%02x is not supported", es.Type)
+				}
+				streamTypes[es.PID] = es.Type
+			}
+
+			pkt.Payload, err = mpegts.RemapPMTStreamTypes(pkt.Payload, clearStreamTypes)
+			if err != nil {
+				return nil, err
+			}
+			b, err := pkt.Marshal()
+			if err != nil {
+				return nil, err
+			}
+			out.Write(b)
+
+		case clearStreamTypes[streamTypes[pkt.PID]] != 0:
+			if pkt.PayloadStart {
+				if err := flush(pkt.PID); err != nil {
+					return nil, err
+				}
+				pes[pkt.PID] = &pending{adaptation: pkt.Adaptation}
+				if _, ok := cc[pkt.PID]; !ok {
+					cc[pkt.PID] = pkt.Continuity
+				}
+			}
+			if p := pes[pkt.PID]; p != nil {
+				p.data = append(p.data, pkt.Payload...)
+			}
+
+		default:
+			out.Write(raw)
+		}
+	}
+
+	pids := make([]int, 0, len(pes))
+	for pid := range pes {
+		pids = append(pids, int(pid))
+	}
+	sort.Ints(pids)
+	for _, pid := range pids {
+		if err := flush(uint16(pid)); err != nil {
+			return nil, err
+		}
+	}
+
+	return out.Bytes(), nil
+}
+
+// decryptH264Samples decrypts the protected slices of an Annex-B access unit.
+//
+// Slice NAL units longer than 48 bytes are encrypted after their first 32
+// bytes in a 1:9 pattern (one encrypted block followed by up to nine clear
+// ones), with emulation prevention applied after encryption.
+func decryptH264Samples(block cipher.Block, iv, payload []byte) []byte {
+	nals := codec.SplitAnnexB(payload)
+
+	for i, nal := range nals {
+		nalType := nal[0] & 0x1F
+		if (nalType != 1 && nalType != 5) || len(nal) <= 48 {
+			continue
+		}
+
+		nal = codec.RemoveEmulationPrevention(nal)
+		dec := cipher.NewCBCDecrypter(block, iv)
+
+		for pos := 32; pos < len(nal); pos += 10 * aes.BlockSize {
+			if len(nal)-pos <= aes.BlockSize {
+				break
+			}
+			dec.CryptBlocks(nal[pos:pos+aes.BlockSize], nal[pos:pos+aes.BlockSize])
+		}
+
+		nals[i] = nal
+	}
+
+	return codec.JoinAnnexB(nals)
+}
+
+// decryptAACSamples decrypts ADTS frames in place: after the header the first
+// 16 bytes stay clear, then every full 16 byte block is encrypted
+func decryptAACSamples(block cipher.Block, iv, payload []byte) error {
+	for off := 0; off < len(payload); {
+		h, err := codec.ParseADTSHeader(payload[off:])
+		if err != nil {
+			return err
+		}
+		if off+h.FrameLen > len(payload) {
+			return errors.New("truncated ADTS frame in SAMPLE-AES stream")
+		}
+
+		body := payload[off+h.HeaderLen : off+h.FrameLen]
+		if len(body) > 16 {
+			enc := body[16:]
+			enc = enc[:len(enc)-len(enc)%aes.BlockSize]
+			if len(enc) > 0 {
+				cipher.NewCBCDecrypter(block, iv).CryptBlocks(enc, enc)
+			}
+		}
+
+		off += h.FrameLen
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package m3u8
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/ajaysinghnp/maya-cli/internal/codec"
+	"github.com/ajaysinghnp/maya-cli/internal/mpegts"
+)
+
+const (
+	videoPID = 0x100
+	audioPID = 0x101
+	pmtPID   = 0x1000
+)
+
+// sampleNAL returns a NAL unit of n bytes: the header byte, then fill, with
+// blocks written at the given offsets
+func sampleNAL(header byte, n int, fill byte, blocks map[int][]byte) []byte {
+	nal := bytes.Repeat([]byte{fill}, n)
+	nal[0] = header
+	for off, b := range blocks {
+		copy(nal[off:], b)
+	}
+	return nal
+}
+
+// adtsFrame returns an ADTS frame (AAC LC, 44.1 kHz, stereo) around body
+func adtsFrame(body []byte) []byte {
+	n := 7 + len(body)
+	h := []byte{0xFF, 0xF1, 0x50, 0x80 | byte(n>>11), byte(n >> 3), byte(n<<5) | 0x1F, 0xFC}
+	return append(h, body...)
+}
+
+// psiPacket wraps a PAT or PMT section body into a TS packet
+func psiPacket(t *testing.T, pid uint16, tableID byte, body []byte) []byte {
+	t.Helper()
+	sec := append([]byte{tableID, 0xB0, 0}, body...)
+	n := len(sec) - 3 + 4 // + CRC
+	sec[1], sec[2] = 0xB0|byte(n>>8), byte(n)
+	crc := mpegts.CRC32(sec)
+	sec = append(sec, byte(crc>>24), byte(crc>>16), byte(crc>>8), byte(crc))
+
+	b, err := (&mpegts.Packet{PID: pid, PayloadStart: true, Payload: append([]byte{0}, sec...)}).Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// pesPacket builds a PES packet with a PTS; video uses the unbounded length
+func pesPacket(streamID byte, pts int64, es []byte) []byte {
+	h := []byte{0, 0, 1, streamID, 0, 0, 0x80, 0x80, 5,
+		0x21 | byte(pts>>29)&0x0E, byte(pts >> 22), byte(pts>>14) | 1, byte(pts >> 7), byte(pts<<1) | 1}
+	if streamID != 0xE0 {
+		n := len(h) - 6 + len(es)
+		h[4], h[5] = byte(n>>8), byte(n)
+	}
+	return append(h, es...)
+}
+
+// sampleAESSegment builds a TS segment with one H.264 and one AAC stream
+// announced with the given PMT stream types
+func sampleAESSegment(t *testing.T, videoType, audioType byte, video, audio []byte) []byte {
+	t.Helper()
+	var ts []byte
+	ts = append(ts, psiPacket(t, 0, 0x00, []byte{0, 1, 0xC1, 0, 0, 0, 1, 0xE0 | pmtPID>>8, pmtPID & 0xFF})...)
+	ts = append(ts, psiPacket(t, pmtPID, 0x02, []byte{0, 1, 0xC1, 0, 0,
+		0xE0 | videoPID>>8, videoPID & 0xFF, 0xF0, 0,
+		videoType, 0xE0 | videoPID>>8, videoPID & 0xFF, 0xF0, 0,
+		audioType, 0xE0 | audioPID>>8, audioPID & 0xFF, 0xF0, 0,
+	})...)
+
+	var cc uint8
+	b, err := mpegts.Packetize(videoPID, &cc, pesPacket(0xE0, 90000, video), []byte{0x50, 0, 0, 0, 0, 0, 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts = append(ts, b...)
+
+	cc = 0
+	b, err = mpegts.Packetize(audioPID, &cc, pesPacket(0xC0, 90000, audio), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return append(ts, b...)
+}
+
+func TestDecryptSampleAES(t *testing.T) {
+	block := func(b []byte, i int) []byte { return b[i*16 : (i+1)*16] }
+
+	// A long IDR slice has one encrypted block every 160 bytes from byte 32,
+	// chained in one CBC run: the NIST ciphertext blocks decrypt to the NIST
+	// plaintext. The second slice restarts the chain with the IV.
+	encIDR := sampleNAL(0x65, 378, 0xAA, map[int][]byte{32: block(nistCipher, 0), 192: block(nistCipher, 1), 352: block(nistCipher, 2)})
+	clearIDR := sampleNAL(0x65, 378, 0xAA, map[int][]byte{32: block(nistPlain, 0), 192: block(nistPlain, 1), 352: block(nistPlain, 2)})
+	encSlice := sampleNAL(0x41, 100, 0xBB, map[int][]byte{32: block(nistCipher, 0)})
+	clearSlice := sampleNAL(0x41, 100, 0xBB, map[int][]byte{32: block(nistPlain, 0)})
+	aud := []byte{0x09, 0xF0}
+	sps := sampleNAL(0x67, 60, 0xCC, nil)        // not a slice, left alone
+	shortSlice := sampleNAL(0x41, 48, 0xDD, nil) // too short to be encrypted
+
+	encVideo := codec.JoinAnnexB([][]byte{aud, sps, encIDR, encSlice, shortSlice})
+	wantVideo := codec.JoinAnnexB([][]byte{aud, sps, clearIDR, clearSlice, shortSlice})
+
+	// ADTS frames keep 16 bytes after the header and a partial last block
+	// clear; every frame restarts the chain
+	clear16 := bytes.Repeat([]byte{0xEE}, 16)
+	tail := []byte{1, 2, 3, 4, 5}
+	join := func(parts ...[]byte) []byte { return bytes.Join(parts, nil) }
+	encAudio := join(
+		adtsFrame(join(clear16, nistCipher, tail)),
+		adtsFrame(join(clear16, block(nistCipher, 0), tail[:3])),
+		adtsFrame(tail),
+	)
+	wantAudio := join(
+		adtsFrame(join(clear16, nistPlain, tail)),
+		adtsFrame(join(clear16, block(nistPlain, 0), tail[:3])),
+		adtsFrame(tail),
+	)
+
+	in := sampleAESSegment(t, mpegts.StreamTypeH264SampleAES, mpegts.StreamTypeAACSampleAES, encVideo, encAudio)
+	out, err := decryptSampleAES(in, nistKey, nistIV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out)%mpegts.PacketSize != 0 {
+		t.Fatalf("output is %d bytes, not whole packets", len(out))
+	}
+	if !bytes.Equal(out[:mpegts.PacketSize], in[:mpegts.PacketSize]) {
+		t.Error("PAT changed")
+	}
+
+	payloads := map[uint16][]byte{}
+	for off := 0; off < len(out); off += mpegts.PacketSize {
+		pkt, err := mpegts.ParsePacket(out[off : off+mpegts.PacketSize])
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch pkt.PID {
+		case pmtPID:
+			pmt, err := mpegts.ParsePMT(pkt.Payload)
+			if err != nil {
+				t.Fatal(err)
+			}
+			types := map[uint16]uint8{}
+			for _, es := range pmt.Streams {
+				types[es.PID] = es.Type
+			}
+			if types[videoPID] != mpegts.StreamTypeH264 || types[audioPID] != mpegts.StreamTypeAAC {
+				t.Errorf("PMT stream types = %v, want clear H.264 and AAC", types)
+			}
+		case videoPID, audioPID:
+			payloads[pkt.PID] = append(payloads[pkt.PID], pkt.Payload...)
+		}
+	}
+
+	for _, c := range []struct {
+		name string
+		pid  uint16
+		want []byte
+	}{
+		{"video", videoPID, wantVideo},
+		{"audio", audioPID, wantAudio},
+	} {
+		pes, err := mpegts.ParsePES(payloads[c.pid])
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if pes.PTS != 90000 {
+			t.Errorf("%s PTS = %d, want 90000", c.name, pes.PTS)
+		}
+		if !bytes.Equal(pes.Payload, c.want) {
+			t.Errorf("%s payload:\n got %x\nwant %x", c.name, pes.Payload, c.want)
+		}
+	}
+}
+
+func TestDecryptSampleAESUnsupported(t *testing.T) {
+	if _, err := decryptSampleAES([]byte("ftyp"), nistKey, nistIV); err == nil || !strings.Contains(err.Error(), "MPEG-TS") {
+		t.Errorf("fMP4 segment: error = %v", err)
+	}
+
+	const ac3SampleAES = 0xC1
+	in := sampleAESSegment(t, mpegts.StreamTypeH264SampleAES, ac3SampleAES, codec.JoinAnnexB([][]byte{{0x09, 0xF0}}), nil)
+	if _, err := decryptSampleAES(in, nistKey, nistIV); err == nil || !strings.Contains(err.Error(), "0xc1") {
+		t.Errorf("AC-3 stream: error = %v", err)
+	}
+}
@@ -0,0 +1,325 @@
+package m3u8
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/ajaysinghnp/maya-cli/internal/httpclient"
+	"github.com/ajaysinghnp/maya-cli/internal/logger/iface"
+	"github.com/ajaysinghnp/maya-cli/internal/progress"
+	"github.com/ajaysinghnp/maya-cli/internal/retry"
+)
+
+// session carries the state shared by every request of one download
+type session struct {
+	ctx      context.Context // cancelled on Ctrl-C, stops every request
+	opts     Options
+	headers  http.Header
+	log      iface.Logger
+	client   *httpclient.Client
+	progress progress.Reporter
+	manifest *manifest
+	result   *Result
+	live     bool // the main playlist was recorded as a live stream
+
+	keysMu sync.Mutex
+	keys   map[string]*keyFetch
+}
+
+func newSession(ctx context.Context, opts Options) *session {
+	client := opts.Client
+	if client == nil {
+		client = httpclient.Default()
+	}
+
+	var reporter progress.Reporter = progress.Nop{}
+	if opts.Progress != nil {
+		reporter = opts.Progress
+	}
+
+	return &session{
+		ctx:      ctx,
+		opts:     opts,
+		headers:  opts.Headers,
+		log:      opts.Log,
+		client:   client,
+		progress: reporter,
+		result:   &Result{URL: opts.URL, Output: opts.Output},
+		keys:     map[string]*keyFetch{},
+	}
+}
+
+// newRequest builds a GET request carrying the client and session headers
+func (s *session) newRequest(ctx context.Context, rawURL string, byteRange *ByteRange) (*http.Request, error) {
+	req, err := s.client.NewRequest(ctx, http.MethodGet, rawURL, s.headers)
+	if err != nil {
+		return nil, err
+	}
+
+	if byteRange != nil {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d",
+			byteRange.Offset, byteRange.Offset+byteRange.Length-1))
+	}
+
+	return req, nil
+}
+
+// get issues a GET request, retrying failures according to the retry policy
+func (s *session) get(rawURL string, byteRange *ByteRange) (*http.Response, error) {
+	req, err := s.newRequest(s.ctx, rawURL, byteRange)
+	if err != nil {
+		return nil, err
+	}
+	return s.client.Do(req)
+}
+
+// fetchPlaylist downloads a playlist and returns its body together with the
+// final URL (after redirects) that relative URIs must be resolved against.
+func (s *session) fetchPlaylist(playlistURL string) ([]byte, *url.URL, error) {
+	resp, err := s.get(playlistURL, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch playlist: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("failed to fetch playlist, status: %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read playlist: %w", err)
+	}
+
+	return data, resp.Request.URL, nil
+}
+
+// segmentPath returns where a segment is stored inside the work directory
+func segmentPath(workDir string, seg Segment) string {
+	return filepath.Join(workDir, fmt.Sprintf("%08d.seg", seg.Sequence))
+}
+
+// downloadSegments fetches all segments into workDir using `workers` goroutines,
+// recording each finished one in the resume manifest. The first error or a
+// cancelled context stops the remaining workers; segments already on disk
+// stay in the manifest for --resume.
+func (s *session) downloadSegments(segments []Segment, workDir string, workers int) error {
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(segments) {
+		workers = len(segments)
+	}
+	if len(segments) == 0 {
+		return nil
+	}
+
+	jobs := make(chan Segment)
+	done := make(chan struct{})
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+		mu       sync.Mutex
+		finished int
+	)
+
+	fail := func(err error) {
+		once.Do(func() {
+			firstErr = err
+			close(done)
+		})
+	}
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for seg := range jobs {
+				size, sum, err := s.downloadSegment(seg, segmentPath(workDir, seg))
+				if err != nil {
+					fail(fmt.Errorf("segment %d: %w", seg.Sequence, err))
+					return
+				}
+				if err := s.manifest.markDone(seg, size, sum); err != nil {
+					fail(fmt.Errorf("failed to update resume manifest: %w", err))
+					return
+				}
+				s.progress.SegmentDone()
+
+				mu.Lock()
+				finished++
+				n := finished
+				mu.Unlock()
+				s.log.Debug(fmt.Sprintf("Segment %d downloaded (%d/%d)", seg.Sequence, n, len(segments)))
+			}
+		}()
+	}
+
+feed:
+	for _, seg := range segments {
+		select {
+		case jobs <- seg:
+		case <-done:
+			break feed
+		case <-s.ctx.Done():
+			fail(s.ctx.Err())
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr != nil && s.ctx.Err() != nil {
+		// report the interruption rather than whichever request it cut short
+		return s.ctx.Err()
+	}
+	return firstErr
+}
+
+// downloadSegment fetches one segment, decrypts it when needed and writes it
+// atomically to dest. It returns the stored size and SHA-256 checksum.
+func (s *session) downloadSegment(seg Segment, dest string) (int64, string, error) {
+	// a dropped connection or truncated body is retried like a bad status
+	var data []byte
+	err := s.client.Retry().Do(s.ctx, func(ctx context.Context) error {
+		var err error
+		data, err = s.fetchSegment(ctx, seg)
+		return err
+	})
+	if err != nil {
+		return 0, "", err
+	}
+
+	if seg.Key != nil {
+		if data, err = s.decrypt(seg, data); err != nil {
+			return 0, "", fmt.Errorf("failed to decrypt: %w", err)
+		}
+	}
+
+	tmp := dest + ".part"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		os.Remove(tmp)
+		return 0, "", err
+	}
+	if err := os.Rename(tmp, dest); err != nil {
+		return 0, "", err
+	}
+
+	sum := sha256.Sum256(data)
+	return int64(len(data)), hex.EncodeToString(sum[:]), nil
+}
+
+// fetchSegment makes a single attempt at reading the complete body of a segment
+func (s *session) fetchSegment(ctx context.Context, seg Segment) ([]byte, error) {
+	req, err := s.newRequest(ctx, seg.URI, seg.ByteRange)
+	if err != nil {
+		return nil, retry.Permanent(err)
+	}
+
+	resp, err := s.client.DoOnce(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	expected := resp.ContentLength
+	var body io.Reader = resp.Body
+	switch {
+	case resp.StatusCode == http.StatusPartialContent:
+	case resp.StatusCode == http.StatusOK && seg.ByteRange != nil:
+		// Server ignored the Range header, cut the sub-range out ourselves
+		if _, err := io.CopyN(io.Discard, resp.Body, seg.ByteRange.Offset); err != nil {
+			return nil, fmt.Errorf("failed to seek to byte range: %w", err)
+		}
+		body = io.LimitReader(resp.Body, seg.ByteRange.Length)
+		expected = seg.ByteRange.Length
+	case resp.StatusCode == http.StatusOK:
+	default:
+		return nil, retry.NewStatusError(resp)
+	}
+
+	data, err := io.ReadAll(&countingReader{r: body, progress: s.progress})
+	if err != nil {
+		return nil, err
+	}
+	if expected >= 0 && int64(len(data)) != expected {
+		return nil, fmt.Errorf("truncated segment: got %d of %d bytes", len(data), expected)
+	}
+
+	return data, nil
+}
+
+// countingReader reports every byte read to the progress reporter
+type countingReader struct {
+	r        io.Reader
+	progress progress.Reporter
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	if n > 0 {
+		c.progress.Bytes(int64(n))
+	}
+	return n, err
+}
+
+// mergeSegments concatenates the downloaded segments in playlist order into
+// output. fMP4 segments are preceded by their init section, written again
+// only when it changes.
+func mergeSegments(segments []Segment, workDir, output string, inits map[*Map]string) error {
+	if err := os.MkdirAll(filepath.Dir(output), 0755); err != nil {
+		return err
+	}
+
+	tmp := output + ".part"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	var current *Map
+	for _, seg := range segments {
+		if seg.Map != nil && seg.Map != current {
+			current = seg.Map
+			if err := appendFile(out, inits[seg.Map]); err != nil {
+				out.Close()
+				os.Remove(tmp)
+				return fmt.Errorf("failed to write init section: %w", err)
+			}
+		}
+
+		if err := appendFile(out, segmentPath(workDir, seg)); err != nil {
+			out.Close()
+			os.Remove(tmp)
+			return fmt.Errorf("failed to merge segment %d: %w", seg.Sequence, err)
+		}
+	}
+
+	if err := out.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	return os.Rename(tmp, output)
+}
+
+func appendFile(dst io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(dst, f)
+	return err
+}
@@ -0,0 +1,189 @@
+package m3u8
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cue is a single timed subtitle
+type cue struct {
+	start time.Duration
+	end   time.Duration
+	text  string
+}
+
+var (
+	// WebVTT tags that have no SRT equivalent (<c.yellow>, <v Bob>, <00:01.000>, ...)
+	vttTagRe = regexp.MustCompile(`</?(?:c|v|lang|ruby|rt)(?:[.\s][^>]*)?>|<\d[\d:.]*>`)
+
+	vttEntities = strings.NewReplacer(
+		"&amp;", "&",
+		"&lt;", "<",
+		"&gt;", ">",
+		"&nbsp;", " ",
+		"&lrm;", "\u200e",
+		"&rlm;", "\u200f",
+	)
+)
+
+// vttToSRT converts one or more concatenated WebVTT documents (as produced
+// by segmented HLS subtitle playlists) into a single SRT file
+func vttToSRT(data []byte) ([]byte, error) {
+	cues, err := parseWebVTT(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	for i, c := range cues {
+		fmt.Fprintf(&b, "%d\n%s --> %s\n%s\n\n", i+1, srtTime(c.start), srtTime(c.end), c.text)
+	}
+	return b.Bytes(), nil
+}
+
+// parseWebVTT extracts cues from concatenated WebVTT documents. Each
+// document may carry an X-TIMESTAMP-MAP; cue times are shifted so they are
+// relative to the MPEG-TS timestamp of the first document.
+func parseWebVTT(data []byte) ([]cue, error) {
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+	text = strings.ReplaceAll(text, "\ufeff", "")
+	// merged segments don't always end with a blank line
+	text = strings.ReplaceAll(text, "\nWEBVTT", "\n\nWEBVTT")
+
+	var (
+		cues    []cue
+		seen    = map[string]bool{}
+		offset  time.Duration
+		firstTS int64 = -1
+	)
+
+	for _, block := range strings.Split(text, "\n\n") {
+		lines := strings.Split(strings.Trim(block, "\n"), "\n")
+		if len(lines) == 0 || lines[0] == "" {
+			continue
+		}
+
+		if strings.HasPrefix(lines[0], "WEBVTT") {
+			offset = 0
+			for _, l := range lines[1:] {
+				if ts, local, ok := parseTimestampMap(l); ok {
+					if firstTS < 0 {
+						firstTS = ts
+					}
+					offset = time.Duration(ts-firstTS)*time.Second/90000 - local
+				}
+			}
+			continue
+		}
+
+		if strings.HasPrefix(lines[0], "NOTE") || lines[0] == "STYLE" || lines[0] == "REGION" {
+			continue
+		}
+
+		// the timing line comes first, or second after an optional cue id
+		timing := 0
+		if !strings.Contains(lines[0], "-->") {
+			timing = 1
+		}
+		if timing >= len(lines) || !strings.Contains(lines[timing], "-->") {
+			continue
+		}
+
+		startStr, rest, _ := strings.Cut(lines[timing], "-->")
+		endStr := strings.Fields(rest)
+		if len(endStr) == 0 {
+			continue
+		}
+
+		start, err := parseVTTTime(startStr)
+		if err != nil {
+			return nil, err
+		}
+		end, err := parseVTTTime(endStr[0])
+		if err != nil {
+			return nil, err
+		}
+
+		body := strings.Join(lines[timing+1:], "\n")
+		body = strings.TrimSpace(vttEntities.Replace(vttTagRe.ReplaceAllString(body, "")))
+		if body == "" {
+			continue
+		}
+
+		c := cue{start: start + offset, end: end + offset, text: body}
+		if c.start < 0 {
+			c.start = 0
+		}
+		if c.end < c.start {
+			continue
+		}
+
+		// segment boundaries often repeat the same cue
+		key := fmt.Sprintf("%d|%d|%s", c.start, c.end, c.text)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		cues = append(cues, c)
+	}
+
+	sort.SliceStable(cues, func(i, j int) bool { return cues[i].start < cues[j].start })
+	return cues, nil
+}
+
+// parseTimestampMap parses "X-TIMESTAMP-MAP=MPEGTS:900000,LOCAL:00:00:00.000"
+func parseTimestampMap(line string) (int64, time.Duration, bool) {
+	v, ok := strings.CutPrefix(strings.TrimSpace(line), "X-TIMESTAMP-MAP=")
+	if !ok {
+		return 0, 0, false
+	}
+
+	var (
+		ts    int64
+		local time.Duration
+	)
+	for _, part := range strings.Split(v, ",") {
+		k, val, _ := strings.Cut(part, ":")
+		switch strings.TrimSpace(k) {
+		case "MPEGTS":
+			ts, _ = strconv.ParseInt(strings.TrimSpace(val), 10, 64)
+		case "LOCAL":
+			local, _ = parseVTTTime(val)
+		}
+	}
+	return ts, local, true
+}
+
+// parseVTTTime parses "hh:mm:ss.ttt" or "mm:ss.ttt"
+func parseVTTTime(s string) (time.Duration, error) {
+	s = strings.ReplaceAll(strings.TrimSpace(s), ",", ".")
+
+	parts := strings.Split(s, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, fmt.Errorf("invalid WebVTT timestamp %q", s)
+	}
+
+	var total float64
+	for _, p := range parts {
+		n, err := strconv.ParseFloat(p, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid WebVTT timestamp %q", s)
+		}
+		total = total*60 + n
+	}
+
+	return time.Duration(total * float64(time.Second)).Round(time.Millisecond), nil
+}
+
+// srtTime formats a duration as "hh:mm:ss,mmm"
+func srtTime(d time.Duration) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d,%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
@@ -1,6 +1,13 @@
 package m3u8
 
-import "github.com/ajaysinghnp/maya-cli/internal/logger/iface"
+import (
+	"net/http"
+	"time"
+
+	"github.com/ajaysinghnp/maya-cli/internal/httpclient"
+	"github.com/ajaysinghnp/maya-cli/internal/logger/iface"
+	"github.com/ajaysinghnp/maya-cli/internal/progress"
+)
 
 type Options struct {
 	URL        string
@@ -8,5 +15,29 @@ type Options struct {
 	TempDir    string // temporary folder for partial downloads
 	Resume     bool
 	Concurrent int
-	Log        iface.Logger // or *logger.Logger depending on your interface design
+	Select     Selection          // variant choice for master playlists
+	Live       bool               // keep polling playlists without EXT-X-ENDLIST
+	Duration   time.Duration      // stop a live recording after this long, 0 = until the stream ends
+	Headers    http.Header        // sent with every playlist, key and segment request
+	Client     *httpclient.Client // nil uses httpclient.Default
+	Progress   progress.Reporter  // nil reports nothing
+	Log        iface.Logger       // or *logger.Logger depending on your interface design
+}
+
+// Track is an alternate audio or subtitle rendition saved next to the video
+type Track struct {
+	Path     string
+	Language string // ISO 639-2 code, "und" when unknown
+	Name     string
+	Default  bool
+	Forced   bool
+}
+
+// Result describes the files produced by a download
+type Result struct {
+	URL       string   // the playlist that was requested
+	Output    string   // the main video file
+	Variant   *Variant // chosen variant, nil for plain media playlists
+	Audio     []Track
+	Subtitles []Track
 }
@@ -0,0 +1,141 @@
+package m3u8
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Selection controls which variant and alternate renditions of a master
+// playlist get downloaded
+type Selection struct {
+	Quality      string // "best", "worst" or a height like "720p"; empty means best
+	MaxBandwidth int64  // upper bound in bits per second, 0 = unlimited
+	PreferCodec  string // e.g. "h264", "hevc", "av1" or a raw CODECS prefix
+
+	Audio     []string // alternate audio languages to save next to the video, "all" for every track
+	Subtitles []string // subtitle languages to save as .srt, "all" for every track
+}
+
+// codecFamilies maps friendly codec names to their RFC 6381 prefixes
+var codecFamilies = map[string][]string{
+	"h264": {"avc1", "avc3"},
+	"avc":  {"avc1", "avc3"},
+	"h265": {"hvc1", "hev1"},
+	"hevc": {"hvc1", "hev1"},
+	"av1":  {"av01"},
+	"vp9":  {"vp09"},
+}
+
+// selectVariant picks the variant matching sel, defaulting to the highest quality
+func selectVariant(variants []Variant, sel Selection) (*Variant, error) {
+	if len(variants) == 0 {
+		return nil, fmt.Errorf("no variants to choose from")
+	}
+
+	candidates := append([]Variant(nil), variants...)
+
+	// highest resolution first, then highest bandwidth
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Height != candidates[j].Height {
+			return candidates[i].Height > candidates[j].Height
+		}
+		return candidates[i].Bandwidth > candidates[j].Bandwidth
+	})
+
+	if sel.MaxBandwidth > 0 {
+		var within []Variant
+		for _, v := range candidates {
+			if v.Bandwidth <= sel.MaxBandwidth {
+				within = append(within, v)
+			}
+		}
+		if len(within) == 0 {
+			return nil, fmt.Errorf("no variant fits within max bandwidth of %d bps", sel.MaxBandwidth)
+		}
+		candidates = within
+	}
+
+	if sel.PreferCodec != "" {
+		var matching []Variant
+		for _, v := range candidates {
+			if matchesCodec(v.Codecs, sel.PreferCodec) {
+				matching = append(matching, v)
+			}
+		}
+		// a preference, not a requirement: keep everything when nothing matches
+		if len(matching) > 0 {
+			candidates = matching
+		}
+	}
+
+	quality := strings.ToLower(strings.TrimSpace(sel.Quality))
+	switch quality {
+	case "", "best", "highest":
+		return &candidates[0], nil
+	case "worst", "lowest":
+		return &candidates[len(candidates)-1], nil
+	}
+
+	height, err := parseQuality(quality)
+	if err != nil {
+		return nil, err
+	}
+
+	// exact match, otherwise the best variant below the requested height
+	for i, v := range candidates {
+		if v.Height == height {
+			return &candidates[i], nil
+		}
+	}
+	for i, v := range candidates {
+		if v.Height > 0 && v.Height < height {
+			return &candidates[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("no variant available at or below %dp", height)
+}
+
+// parseQuality turns "720p", "720" or "4k" into a frame height
+func parseQuality(q string) (int, error) {
+	switch q {
+	case "4k", "uhd":
+		return 2160, nil
+	case "2k":
+		return 1440, nil
+	case "fhd":
+		return 1080, nil
+	case "hd":
+		return 720, nil
+	case "sd":
+		return 480, nil
+	}
+
+	h, err := strconv.Atoi(strings.TrimSuffix(q, "p"))
+	if err != nil || h <= 0 {
+		return 0, fmt.Errorf("invalid quality %q (use e.g. 1080p, 720p, best, worst)", q)
+	}
+	return h, nil
+}
+
+// matchesCodec reports whether a CODECS attribute contains the preferred codec
+func matchesCodec(codecs, prefer string) bool {
+	prefer = strings.ToLower(strings.TrimSpace(prefer))
+
+	prefixes, ok := codecFamilies[prefer]
+	if !ok {
+		prefixes = []string{prefer}
+	}
+
+	for _, c := range strings.Split(strings.ToLower(codecs), ",") {
+		c = strings.TrimSpace(c)
+		for _, p := range prefixes {
+			if strings.HasPrefix(c, p) {
+				return true
+			}
+		}
+	}
+	return false
+}
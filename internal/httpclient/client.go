@@ -0,0 +1,223 @@
+package httpclient
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ajaysinghnp/maya-cli/internal/logger/iface"
+	"github.com/ajaysinghnp/maya-cli/internal/ratelimit"
+	"github.com/ajaysinghnp/maya-cli/internal/retry"
+)
+
+// Config holds the HTTP settings shared by every resolver and downloader
+type Config struct {
+	Profile        string        // header profile, see Profiles; empty means DefaultProfile
+	Headers        http.Header   // extra headers sent with every request
+	HostRules      []HostRule    // per-host Referer/Origin overrides
+	Proxy          string        // http://, https:// or socks5:// proxy URL; empty uses the environment
+	Insecure       bool          // skip TLS certificate verification
+	CACert         string        // PEM file with extra trusted certificates
+	ConnectTimeout time.Duration // limit for dialing, the TLS handshake and response headers, 0 = none
+	Retry          retry.Policy  // applied by Do
+	RateLimit      int64         // bytes per second across all response bodies, 0 = unlimited
+	HostRateLimits []HostLimit   // additional per-host caps
+}
+
+// Client is an http.Client with a cookie jar, header profiles and a retry policy
+type Client struct {
+	http    *http.Client
+	cfg     Config
+	profile http.Header
+	retry   retry.Policy
+
+	limit      *ratelimit.Limiter
+	hostLimits []hostLimiter
+}
+
+// New builds a client from cfg
+func New(cfg Config, log iface.Logger) (*Client, error) {
+	profile, err := Profile(cfg.Profile)
+	if err != nil {
+		return nil, err
+	}
+
+	transport, err := newTransport(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := cfg.Retry
+	policy.Log = log
+
+	c := &Client{
+		http:    &http.Client{Transport: transport, Jar: jar},
+		cfg:     cfg,
+		profile: profile,
+		retry:   policy,
+		limit:   ratelimit.New(cfg.RateLimit),
+	}
+	for _, hl := range cfg.HostRateLimits {
+		c.hostLimits = append(c.hostLimits, hostLimiter{
+			host:    strings.ToLower(hl.Host),
+			limiter: ratelimit.New(hl.BytesPerSec),
+		})
+	}
+
+	return c, nil
+}
+
+// Default returns a client with the default profile that tries every request once
+func Default() *Client {
+	c, _ := New(Config{}, nil)
+	return c
+}
+
+// Retry returns the retry policy of the client, for operations that go
+// beyond a single request (e.g. reading and validating a whole segment)
+func (c *Client) Retry() retry.Policy {
+	return c.retry
+}
+
+// NewRequest builds a request from the profile headers, the headers a
+// source needs (may be nil), the user's extra headers and the Referer/Origin
+// rule for its host. Later layers win, so user settings beat built-in ones.
+func (c *Client) NewRequest(ctx context.Context, method, rawURL string, header http.Header) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, layer := range []http.Header{c.profile, header, c.cfg.Headers} {
+		for k, v := range layer {
+			req.Header[k] = append([]string(nil), v...)
+		}
+	}
+	if rule := matchHost(c.cfg.HostRules, req.URL.Hostname()); rule != nil {
+		rule.apply(req.Header)
+	}
+
+	return req, nil
+}
+
+// Get is a shortcut for a GET request sent with Do
+func (c *Client) Get(ctx context.Context, rawURL string, header http.Header) (*http.Response, error) {
+	req, err := c.NewRequest(ctx, http.MethodGet, rawURL, header)
+	if err != nil {
+		return nil, err
+	}
+	return c.Do(req)
+}
+
+// Do sends req, retrying failures according to the retry policy
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	prepare(req)
+	resp, err := c.retry.Request(c.http, req)
+	if err != nil {
+		return nil, err
+	}
+	c.throttle(resp)
+	return resp, nil
+}
+
+// DoOnce sends req exactly once
+func (c *Client) DoOnce(req *http.Request) (*http.Response, error) {
+	prepare(req)
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	c.throttle(resp)
+	return resp, nil
+}
+
+// throttle makes the response body draw from the global and per-host rate limits
+func (c *Client) throttle(resp *http.Response) {
+	req := resp.Request
+	resp.Body = ratelimit.ReadCloser(req.Context(), resp.Body, c.limit, c.hostLimiter(req.URL.Hostname()))
+}
+
+// prepare lets the transport negotiate (and transparently decode) compression;
+// a hand-set Accept-Encoding would hand callers compressed bodies
+func prepare(req *http.Request) {
+	req.Header.Del("Accept-Encoding")
+}
+
+// newTransport configures proxy, TLS and connect timeouts
+func newTransport(cfg Config) (*http.Transport, error) {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+
+	if cfg.Proxy != "" {
+		proxyURL, err := parseProxy(cfg.Proxy)
+		if err != nil {
+			return nil, err
+		}
+		t.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	if cfg.Insecure || cfg.CACert != "" {
+		tlsConfig := &tls.Config{InsecureSkipVerify: cfg.Insecure}
+
+		if cfg.CACert != "" {
+			pem, err := os.ReadFile(cfg.CACert)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read CA certificate: %w", err)
+			}
+			pool, err := x509.SystemCertPool()
+			if err != nil {
+				pool = x509.NewCertPool()
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificates found in %s", cfg.CACert)
+			}
+			tlsConfig.RootCAs = pool
+		}
+
+		t.TLSClientConfig = tlsConfig
+	}
+
+	if cfg.ConnectTimeout > 0 {
+		dialer := &net.Dialer{Timeout: cfg.ConnectTimeout, KeepAlive: 30 * time.Second}
+		t.DialContext = dialer.DialContext
+		t.TLSHandshakeTimeout = cfg.ConnectTimeout
+		t.ResponseHeaderTimeout = cfg.ConnectTimeout
+	}
+
+	return t, nil
+}
+
+// parseProxy validates a proxy URL; the scheme defaults to http
+func parseProxy(raw string) (*url.URL, error) {
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy %q: %w", raw, err)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy %q: missing host", raw)
+	}
+
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+		return u, nil
+	default:
+		return nil, errors.New("unsupported proxy scheme " + u.Scheme + " (use http, https or socks5)")
+	}
+}
@@ -0,0 +1,129 @@
+package httpclient
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// DefaultProfile is used when no header profile is configured
+const DefaultProfile = "firefox"
+
+// Profiles are named sets of headers that make requests look like a given client
+var Profiles = map[string]http.Header{
+	"firefox": {
+		"User-Agent":      {"Mozilla/5.0 (Macintosh; Intel Mac OS X 10.15; rv:148.0) Gecko/20100101 Firefox/148.0"},
+		"Accept":          {"*/*"},
+		"Accept-Language": {"en-US,en;q=0.9"},
+	},
+	"chrome": {
+		"User-Agent":         {"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/140.0.0.0 Safari/537.36"},
+		"Accept":             {"*/*"},
+		"Accept-Language":    {"en-US,en;q=0.9"},
+		"Sec-Ch-Ua":          {`"Chromium";v="140", "Not=A?Brand";v="24", "Google Chrome";v="140"`},
+		"Sec-Ch-Ua-Mobile":   {"?0"},
+		"Sec-Ch-Ua-Platform": {`"Windows"`},
+	},
+	"safari": {
+		"User-Agent":      {"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/18.0 Safari/605.1.15"},
+		"Accept":          {"*/*"},
+		"Accept-Language": {"en-US,en;q=0.9"},
+	},
+	"curl": {
+		"User-Agent": {"curl/8.9.1"},
+		"Accept":     {"*/*"},
+	},
+	// Go's own defaults, nothing added
+	"none": {},
+}
+
+// Profile returns a copy of the named header profile
+func Profile(name string) (http.Header, error) {
+	if name == "" {
+		name = DefaultProfile
+	}
+	h, ok := Profiles[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown header profile %q (available: %s)", name, strings.Join(ProfileNames(), ", "))
+	}
+	return h.Clone(), nil
+}
+
+// ProfileNames lists the available header profiles
+func ProfileNames() []string {
+	names := make([]string, 0, len(Profiles))
+	for n := range Profiles {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// HostRule sets the Referer and Origin sent to a host and its subdomains
+type HostRule struct {
+	Host    string
+	Referer string
+	Origin  string // derived from Referer when empty
+}
+
+// ParseHostRule parses "host=referer", e.g. "cdn.example.com=https://player.example.com/"
+func ParseHostRule(v string) (HostRule, error) {
+	host, referer, ok := strings.Cut(v, "=")
+	host = strings.ToLower(strings.TrimSpace(host))
+	referer = strings.TrimSpace(referer)
+	if !ok || host == "" || referer == "" {
+		return HostRule{}, fmt.Errorf("invalid host rule %q (use host=referer)", v)
+	}
+
+	u, err := url.Parse(referer)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return HostRule{}, fmt.Errorf("invalid referer in host rule %q", v)
+	}
+
+	return HostRule{Host: host, Referer: referer}, nil
+}
+
+// ParseHeader parses a "Name: value" header line
+func ParseHeader(v string) (string, string, error) {
+	name, value, ok := strings.Cut(v, ":")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" {
+		return "", "", fmt.Errorf("invalid header %q (use \"Name: value\")", v)
+	}
+	return http.CanonicalHeaderKey(name), strings.TrimSpace(value), nil
+}
+
+func (r *HostRule) apply(h http.Header) {
+	if r.Referer != "" {
+		h.Set("Referer", r.Referer)
+	}
+
+	origin := r.Origin
+	if origin == "" && r.Referer != "" {
+		if u, err := url.Parse(r.Referer); err == nil {
+			origin = u.Scheme + "://" + u.Host
+		}
+	}
+	if origin != "" {
+		h.Set("Origin", origin)
+	}
+}
+
+// matchHost returns the most specific rule for host, nil when none applies
+func matchHost(rules []HostRule, host string) *HostRule {
+	host = strings.ToLower(host)
+
+	var best *HostRule
+	for i := range rules {
+		r := &rules[i]
+		if host != r.Host && !strings.HasSuffix(host, "."+r.Host) {
+			continue
+		}
+		if best == nil || len(r.Host) > len(best.Host) {
+			best = r
+		}
+	}
+	return best
+}
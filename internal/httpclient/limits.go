@@ -0,0 +1,60 @@
+package httpclient
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ajaysinghnp/maya-cli/internal/ratelimit"
+	"github.com/ajaysinghnp/maya-cli/utils"
+)
+
+// HostLimit caps the download rate from a host and its subdomains
+type HostLimit struct {
+	Host        string
+	BytesPerSec int64
+}
+
+// ParseHostLimit parses "host=rate", e.g. "cdn.example.com=1M"
+func ParseHostLimit(v string) (HostLimit, error) {
+	host, rate, ok := strings.Cut(v, "=")
+	host = strings.ToLower(strings.TrimSpace(host))
+	if !ok || host == "" {
+		return HostLimit{}, fmt.Errorf("invalid host rate limit %q (use host=rate)", v)
+	}
+
+	n, err := utils.ParseByteSize(rate)
+	if err != nil {
+		return HostLimit{}, err
+	}
+	if n <= 0 {
+		return HostLimit{}, fmt.Errorf("invalid host rate limit %q: rate must be positive", v)
+	}
+
+	return HostLimit{Host: host, BytesPerSec: n}, nil
+}
+
+type hostLimiter struct {
+	host    string
+	limiter *ratelimit.Limiter
+}
+
+// hostLimiter returns the limiter of the most specific host limit for host
+func (c *Client) hostLimiter(host string) *ratelimit.Limiter {
+	host = strings.ToLower(host)
+
+	var best *hostLimiter
+	for i := range c.hostLimits {
+		hl := &c.hostLimits[i]
+		if host != hl.host && !strings.HasSuffix(host, "."+hl.host) {
+			continue
+		}
+		if best == nil || len(hl.host) > len(best.host) {
+			best = hl
+		}
+	}
+
+	if best == nil {
+		return nil
+	}
+	return best.limiter
+}
@@ -0,0 +1,14 @@
+package iface
+
+// Overlay is a live status line, like a progress bar, kept below log output
+type Overlay interface {
+	Line() string // current status, empty when there is nothing to show
+}
+
+// OverlayLogger is a Logger that can keep an Overlay below its messages so
+// the two don't shred each other
+type OverlayLogger interface {
+	Logger
+	SetOverlay(o Overlay) // nil removes the overlay
+	Redraw()              // refreshes the overlay line
+}
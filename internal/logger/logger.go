@@ -16,6 +16,9 @@ type Logger struct {
 	file      *os.File
 	writeFile bool
 	mu        sync.Mutex
+
+	overlay      iface.Overlay
+	overlayDrawn bool // the terminal's last line is the overlay
 }
 
 // ANSI color codes
@@ -80,8 +83,10 @@ func (l *Logger) logMessage(level, labelColor, textColor, msg string) {
 		textColor, timestamp+" "+msg, reset,
 	)
 
-	// Always log to terminal
+	// Always log to terminal, above the overlay
+	l.clearOverlay()
 	fmt.Print(formatted)
+	l.drawOverlay()
 
 	// Only log to file if enabled
 	if l.writeFile {
@@ -89,6 +94,44 @@ func (l *Logger) logMessage(level, labelColor, textColor, msg string) {
 	}
 }
 
+// SetOverlay keeps o as a live line below the log messages; nil removes it
+func (l *Logger) SetOverlay(o iface.Overlay) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.clearOverlay()
+	l.overlay = o
+	l.drawOverlay()
+}
+
+// Redraw refreshes the overlay line
+func (l *Logger) Redraw() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.clearOverlay()
+	l.drawOverlay()
+}
+
+// clearOverlay erases the overlay line; callers hold l.mu
+func (l *Logger) clearOverlay() {
+	if l.overlayDrawn {
+		fmt.Print("\r\033[K")
+		l.overlayDrawn = false
+	}
+}
+
+// drawOverlay prints the overlay without a newline; callers hold l.mu
+func (l *Logger) drawOverlay() {
+	if l.overlay == nil {
+		return
+	}
+	if line := l.overlay.Line(); line != "" {
+		fmt.Print(line)
+		l.overlayDrawn = true
+	}
+}
+
 func (l *Logger) Info(msg string) {
 	l.logMessage("INFO", bgCyan+white, cyan, msg)
 }
@@ -170,4 +213,4 @@ func LogMetadata(m *metadata.Metadata) {
 }
 
 // Implement the iface.Logger interface
-var _ iface.Logger = (*Logger)(nil)
+var _ iface.OverlayLogger = (*Logger)(nil)
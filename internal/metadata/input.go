@@ -0,0 +1,144 @@
+package metadata
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Manual is metadata supplied by the user instead of scraped, from flags
+// and/or a JSON file in the Metadata format
+type Manual struct {
+	File    string // JSON file, the other fields override its values
+	Title   string
+	Year    int
+	Type    MediaType
+	Season  int
+	Episode int
+	TMDB    string
+	IMDB    string
+
+	Prompt bool // ask on stdin for missing required fields
+}
+
+// empty reports whether nothing was supplied at all
+func (in Manual) empty() bool {
+	return in.File == "" && in.Title == "" && in.Year == 0 && in.Type == "" &&
+		in.Season == 0 && in.Episode == 0 && in.TMDB == "" && in.IMDB == ""
+}
+
+// FromManual builds metadata from the user input. Missing required fields are
+// prompted for when in.Prompt is set and reported as an error otherwise.
+func FromManual(in Manual, log Logger) (*Metadata, error) {
+	// nothing given: keep the full interactive questionnaire
+	if in.empty() && in.Prompt {
+		meta, err := PromptUser(log)
+		if err != nil {
+			return nil, err
+		}
+		return meta, meta.validateType()
+	}
+
+	meta := &Metadata{}
+	if in.File != "" {
+		loaded, err := LoadFile(in.File)
+		if err != nil {
+			return nil, err
+		}
+		meta = loaded
+		log.Info("Loaded metadata from " + in.File)
+	}
+
+	in.apply(meta)
+
+	if err := meta.validateType(); err != nil {
+		return nil, err
+	}
+
+	if missing := meta.missing(); len(missing) > 0 {
+		if !in.Prompt {
+			return nil, fmt.Errorf("metadata incomplete, missing %s (use --meta or the matching flags)",
+				strings.Join(missing, ", "))
+		}
+		if err := PromptMissing(meta, log); err != nil {
+			return nil, err
+		}
+		if err := meta.validateType(); err != nil {
+			return nil, err
+		}
+	}
+
+	return meta, nil
+}
+
+// LoadFile reads metadata from a JSON file using the Metadata field names,
+// e.g. {"title": "...", "releaseYear": 2024, "type": "movie"}
+func LoadFile(path string) (*Metadata, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var meta Metadata
+	if err := json.Unmarshal(b, &meta); err != nil {
+		return nil, fmt.Errorf("metadata file %s: %w", path, err)
+	}
+	meta.Type = MediaType(strings.ToLower(strings.TrimSpace(string(meta.Type))))
+	return &meta, nil
+}
+
+// apply overrides meta with every field that was set
+func (in Manual) apply(meta *Metadata) {
+	if in.Title != "" {
+		meta.Title = strings.TrimSpace(in.Title)
+	}
+	if in.Year != 0 {
+		meta.Year = in.Year
+	}
+	if in.Type != "" {
+		meta.Type = MediaType(strings.ToLower(strings.TrimSpace(string(in.Type))))
+	}
+	if in.Season != 0 {
+		meta.Season = in.Season
+	}
+	if in.Episode != 0 {
+		meta.Episode = in.Episode
+	}
+	if in.TMDB != "" {
+		meta.IDs.TMDB = strings.TrimSpace(in.TMDB)
+	}
+	if in.IMDB != "" {
+		meta.IDs.IMDB = strings.TrimSpace(in.IMDB)
+	}
+}
+
+// missing lists the flags for the required fields that are still empty
+func (m *Metadata) missing() []string {
+	var missing []string
+	if m.Type == "" {
+		missing = append(missing, "--type")
+	}
+	if m.Title == "" {
+		missing = append(missing, "--title")
+	}
+	if m.Type == Series {
+		if m.Season == 0 {
+			missing = append(missing, "--season")
+		}
+		if m.Episode == 0 {
+			missing = append(missing, "--episode")
+		}
+	}
+	return missing
+}
+
+func (m *Metadata) validateType() error {
+	switch m.Type {
+	case "", Movie, Series:
+		return nil
+	default:
+		return errors.New("invalid media type " + string(m.Type) + " (use movie or series)")
+	}
+}
@@ -18,6 +18,10 @@ type Source struct {
 	Label    string `json:"label"`              // e.g., "Hindi (No Ads)"
 	URL      string `json:"source"`             // the m3u8 or download link
 	LabelTag string `json:"labelTag,omitempty"` // optional
+
+	// Inherited marks a show source listed for an episode without its own;
+	// it plays the show, not the episode
+	Inherited bool `json:"-"`
 }
 
 // Main metadata struct
@@ -32,6 +36,7 @@ type Metadata struct {
 	ReleaseDate string `json:"fullReleaseDate"`
 
 	// Content
+	Plot     string   `json:"description,omitempty"`
 	Language string   `json:"language"`
 	Genres   []string `json:"genre"`
 	Cast     []string `json:"castDetails"`
@@ -41,13 +46,20 @@ type Metadata struct {
 
 	// Media
 	Thumbnail string   `json:"thumbnail"`
+	Fanart    string   `json:"backdrop,omitempty"`
 	Sources   []Source `json:"watchLink"` // renamed from WatchLink
 
 	// TV-only (optional)
-	Season       int    `json:"season,omitempty"`
-	Episode      int    `json:"episode,omitempty"`
-	EpisodeTitle string `json:"episodeTitle,omitempty"`
-	SeasonDir    string `json:"seasonDir,omitempty"`
+	Season           int    `json:"season,omitempty"`
+	Episode          int    `json:"episode,omitempty"`
+	EpisodeTitle     string `json:"episodeTitle,omitempty"`
+	EpisodeThumbnail string `json:"episodeThumbnail,omitempty"`
+	EpisodePlot      string `json:"episodePlot,omitempty"`
+	EpisodeAired     string `json:"episodeAired,omitempty"`
+	SeasonDir        string `json:"seasonDir,omitempty"`
+
+	// Series pages: every season and episode the source offers
+	Seasons []Season `json:"seasons,omitempty"`
 
 	// Filesystem (runtime)
 	RootDir   string `json:"-"`
@@ -1,47 +1,207 @@
 package metadata
 
 import (
+	"encoding/xml"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
-func WriteMovieNFO(dir string, m *Metadata) error {
-	content := fmt.Sprintf(
-		`<movie>
-  <title>%s</title>
-  <year>%s</year>
-  <imdbid>%s</imdbid>
-  <tmdbid>%s</tmdbid>
-</movie>`,
-		m.Title,
-		m.Year,
-		m.IDs.IMDB,
-		m.IDs.TMDB,
-	)
-
-	return os.WriteFile(
-		filepath.Join(dir, "movie.nfo"),
-		[]byte(content),
-		0644,
-	)
-}
-
-func WriteEpisodeNFO(dir string, m *Metadata) error {
-	content := fmt.Sprintf(
-		`<episodedetails>
-  <title>%s</title>
-  <season>%d</season>
-  <episode>%d</episode>
-</episodedetails>`,
-		m.EpisodeTitle,
-		m.Season,
-		m.Episode,
-	)
-
-	return os.WriteFile(
-		filepath.Join(dir, fmt.Sprintf("episode-%02d.nfo", m.Episode)),
-		[]byte(content),
-		0644,
-	)
+// NFO documents follow the Kodi format, which Jellyfin and Emby read as well
+
+type uniqueID struct {
+	Type    string `xml:"type,attr"`
+	Default bool   `xml:"default,attr,omitempty"`
+	Value   string `xml:",chardata"`
+}
+
+type actor struct {
+	Name  string `xml:"name"`
+	Order int    `xml:"order"`
+}
+
+type movieNFO struct {
+	XMLName   xml.Name   `xml:"movie"`
+	Title     string     `xml:"title"`
+	Year      int        `xml:"year,omitempty"`
+	Plot      string     `xml:"plot,omitempty"`
+	Premiered string     `xml:"premiered,omitempty"`
+	Language  string     `xml:"language,omitempty"`
+	Genres    []string   `xml:"genre"`
+	UniqueIDs []uniqueID `xml:"uniqueid"`
+	Thumb     string     `xml:"thumb,omitempty"`
+	Actors    []actor    `xml:"actor"`
+}
+
+type tvShowNFO struct {
+	XMLName   xml.Name   `xml:"tvshow"`
+	Title     string     `xml:"title"`
+	Year      int        `xml:"year,omitempty"`
+	Plot      string     `xml:"plot,omitempty"`
+	Premiered string     `xml:"premiered,omitempty"`
+	Language  string     `xml:"language,omitempty"`
+	Genres    []string   `xml:"genre"`
+	UniqueIDs []uniqueID `xml:"uniqueid"`
+	Thumb     string     `xml:"thumb,omitempty"`
+	Actors    []actor    `xml:"actor"`
+}
+
+type seasonNFO struct {
+	XMLName      xml.Name `xml:"season"`
+	Title        string   `xml:"title"`
+	SeasonNumber int      `xml:"seasonnumber"`
+	Year         int      `xml:"year,omitempty"`
+}
+
+type episodeNFO struct {
+	XMLName   xml.Name `xml:"episodedetails"`
+	Title     string   `xml:"title"`
+	ShowTitle string   `xml:"showtitle,omitempty"`
+	Season    int      `xml:"season"`
+	Episode   int      `xml:"episode"`
+	Year      int      `xml:"year,omitempty"`
+	Plot      string   `xml:"plot,omitempty"`
+	Aired     string   `xml:"aired,omitempty"`
+	Language  string   `xml:"language,omitempty"`
+	Actors    []actor  `xml:"actor"`
+}
+
+// WriteNFOs writes every NFO that belongs to m into its RootDir (and
+// SeasonDir for series) and returns the written paths. BuildPaths must have
+// run first.
+func WriteNFOs(m *Metadata) ([]string, error) {
+	if m.RootDir == "" {
+		return nil, fmt.Errorf("NFO: media paths not built")
+	}
+
+	var written []string
+	write := func(path string, err error) error {
+		if err == nil {
+			written = append(written, path)
+		}
+		return err
+	}
+
+	if m.Type != Series {
+		err := write(WriteMovieNFO(m.RootDir, m))
+		return written, err
+	}
+
+	if err := write(WriteTVShowNFO(m.RootDir, m)); err != nil {
+		return written, err
+	}
+	if err := write(WriteSeasonNFO(m.SeasonDir, m)); err != nil {
+		return written, err
+	}
+	err := write(WriteEpisodeNFO(m.SeasonDir, m))
+	return written, err
+}
+
+// WriteMovieNFO writes movie.nfo into dir
+func WriteMovieNFO(dir string, m *Metadata) (string, error) {
+	return writeNFO(filepath.Join(dir, "movie.nfo"), movieNFO{
+		Title:     m.Title,
+		Year:      m.Year,
+		Plot:      m.Plot,
+		Premiered: m.ReleaseDate,
+		Language:  m.Language,
+		Genres:    m.Genres,
+		UniqueIDs: uniqueIDs(m.IDs),
+		Thumb:     m.Thumbnail,
+		Actors:    actors(m.Cast),
+	})
+}
+
+// WriteTVShowNFO writes tvshow.nfo into dir, the series root
+func WriteTVShowNFO(dir string, m *Metadata) (string, error) {
+	return writeNFO(filepath.Join(dir, "tvshow.nfo"), tvShowNFO{
+		Title:     m.Title,
+		Year:      m.Year,
+		Plot:      m.Plot,
+		Premiered: m.ReleaseDate,
+		Language:  m.Language,
+		Genres:    m.Genres,
+		UniqueIDs: uniqueIDs(m.IDs),
+		Thumb:     m.Thumbnail,
+		Actors:    actors(m.Cast),
+	})
+}
+
+// WriteSeasonNFO writes season.nfo into dir, the season directory
+func WriteSeasonNFO(dir string, m *Metadata) (string, error) {
+	return writeNFO(filepath.Join(dir, "season.nfo"), seasonNFO{
+		Title:        fmt.Sprintf("Season %d", m.Season),
+		SeasonNumber: m.Season,
+		Year:         m.Year,
+	})
+}
+
+// WriteEpisodeNFO writes the episode NFO into dir, named after the media
+// file so Jellyfin pairs them
+func WriteEpisodeNFO(dir string, m *Metadata) (string, error) {
+	name := fmt.Sprintf("episode-%02d.nfo", m.Episode)
+	if m.MediaFile != "" {
+		base := filepath.Base(m.MediaFile)
+		name = strings.TrimSuffix(base, filepath.Ext(base)) + ".nfo"
+	}
+
+	title := m.EpisodeTitle
+	if title == "" {
+		title = fmt.Sprintf("Episode %d", m.Episode)
+	}
+
+	return writeNFO(filepath.Join(dir, name), episodeNFO{
+		Title:     title,
+		ShowTitle: m.Title,
+		Season:    m.Season,
+		Episode:   m.Episode,
+		Year:      m.Year,
+		Plot:      m.EpisodePlot,
+		Aired:     m.EpisodeAired,
+		Language:  m.Language,
+		Actors:    actors(m.Cast),
+	})
+}
+
+// writeNFO marshals doc with an XML header; encoding/xml escapes the text
+func writeNFO(path string, doc any) (string, error) {
+	b, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+
+	content := append([]byte(xml.Header), b...)
+	content = append(content, '\n')
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// uniqueIDs lists the known ids, IMDB first as the default scraper id
+func uniqueIDs(ids IDs) []uniqueID {
+	var out []uniqueID
+	if ids.IMDB != "" {
+		out = append(out, uniqueID{Type: "imdb", Value: ids.IMDB})
+	}
+	if ids.TMDB != "" {
+		out = append(out, uniqueID{Type: "tmdb", Value: ids.TMDB})
+	}
+	if len(out) > 0 {
+		out[0].Default = true
+	}
+	return out
+}
+
+func actors(cast []string) []actor {
+	var out []actor
+	for i, name := range cast {
+		out = append(out, actor{Name: name, Order: i})
+	}
+	return out
 }
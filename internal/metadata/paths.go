@@ -49,24 +49,22 @@ func (m *Metadata) BuildPaths(base string, ext string, log iface.Logger) {
 
 	m.RootDir = filepath.Join(
 		base,
-		fmt.Sprintf("%s (%s)%s", m.Title, m.Year, idPart),
+		fmt.Sprintf("%s (%d)%s", m.Title, m.Year, idPart),
 	)
 
 	m.SeasonDir = filepath.Join(
 		m.RootDir,
-		fmt.Sprintf("Season %02d (%s)", m.Season, m.Year),
+		fmt.Sprintf("Season %02d", m.Season),
 	)
 
+	name := fmt.Sprintf("%s - S%02dE%02d", m.Title, m.Season, m.Episode)
+	if m.EpisodeTitle != "" {
+		name += " - " + m.EpisodeTitle
+	}
+
 	m.MediaFile = filepath.Join(
 		m.SeasonDir,
-		fmt.Sprintf(
-			"%s - S%02dE%02d - %s.%s",
-			m.Title,
-			m.Season,
-			m.Episode,
-			m.EpisodeTitle,
-			ext,
-		),
+		name+"."+ext,
 	)
 
 	log.Success("Series root directory: " + m.RootDir)
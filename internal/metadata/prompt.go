@@ -52,3 +52,41 @@ func PromptUser(log Logger) (*Metadata, error) {
 
 	return meta, nil
 }
+
+// PromptMissing asks only for the required fields meta doesn't have yet
+func PromptMissing(meta *Metadata, log Logger) error {
+	reader := bufio.NewReader(os.Stdin)
+
+	log.Info("Some metadata is missing, please fill it in")
+
+	if meta.Type == "" {
+		fmt.Print("Is this a movie or series? (movie/series): ")
+		t, _ := reader.ReadString('\n')
+		meta.Type = MediaType(strings.TrimSpace(strings.ToLower(t)))
+	}
+
+	if meta.Title == "" {
+		fmt.Print("Title: ")
+		meta.Title, _ = reader.ReadString('\n')
+		meta.Title = strings.TrimSpace(meta.Title)
+	}
+
+	if meta.Type == Series {
+		if meta.Season == 0 {
+			fmt.Print("Season number: ")
+			s, _ := reader.ReadString('\n')
+			meta.Season, _ = strconv.Atoi(strings.TrimSpace(s))
+		}
+
+		if meta.Episode == 0 {
+			fmt.Print("Episode number: ")
+			e, _ := reader.ReadString('\n')
+			meta.Episode, _ = strconv.Atoi(strings.TrimSpace(e))
+		}
+	}
+
+	if missing := meta.missing(); len(missing) > 0 {
+		return fmt.Errorf("metadata incomplete, missing %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
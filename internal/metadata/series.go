@@ -0,0 +1,160 @@
+package metadata
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Season is one season of a series with the episodes a source offers
+type Season struct {
+	Number   int       `json:"season"`
+	Episodes []Episode `json:"episodes"`
+}
+
+// Episode is a single episode and the sources it can be played from. Like
+// Metadata, the field names follow the series pages' movieDetails.
+type Episode struct {
+	Number    int      `json:"episode"`
+	Title     string   `json:"title,omitempty"`
+	Plot      string   `json:"description,omitempty"`
+	Aired     string   `json:"airDate,omitempty"`
+	Thumbnail string   `json:"thumbnail,omitempty"`
+	Sources   []Source `json:"watchLink,omitempty"`
+}
+
+// ForEpisode returns a copy of the show metadata describing one episode,
+// ready for BuildPaths
+func (m *Metadata) ForEpisode(season int, ep Episode) *Metadata {
+	c := *m
+	c.Type = Series
+	c.Seasons = nil
+	c.Season = season
+	c.Episode = ep.Number
+	c.EpisodeTitle = ep.Title
+	c.EpisodePlot = ep.Plot
+	c.EpisodeAired = ep.Aired
+	c.EpisodeThumbnail = ep.Thumbnail
+	// pages without per-episode sources list the show's, marked so providers
+	// don't mistake them for the episode's own playlist
+	if len(ep.Sources) > 0 {
+		c.Sources = ep.Sources
+	} else {
+		c.Sources = make([]Source, len(m.Sources))
+		for i, s := range m.Sources {
+			s.Inherited = true
+			c.Sources[i] = s
+		}
+	}
+	c.RootDir, c.SeasonDir, c.MediaFile = "", "", ""
+	return &c
+}
+
+// EpisodeSet is a set of episode numbers parsed from "1-5,8"; the zero
+// value contains every episode
+type EpisodeSet struct {
+	ranges [][2]int
+}
+
+// ParseEpisodes parses a comma separated list of episode numbers and
+// inclusive ranges. An empty string or "all" selects every episode.
+func ParseEpisodes(v string) (EpisodeSet, error) {
+	var set EpisodeSet
+	if strings.EqualFold(strings.TrimSpace(v), "all") {
+		return set, nil // what String returns for the zero value
+	}
+	for _, part := range strings.Split(v, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		from, to, isRange := strings.Cut(part, "-")
+		lo, err := strconv.Atoi(strings.TrimSpace(from))
+		if err != nil || lo < 1 {
+			return EpisodeSet{}, fmt.Errorf("invalid episode %q (use e.g. 1-5,8)", part)
+		}
+		hi := lo
+		if isRange {
+			hi, err = strconv.Atoi(strings.TrimSpace(to))
+			if err != nil || hi < lo {
+				return EpisodeSet{}, fmt.Errorf("invalid episode range %q (use e.g. 1-5,8)", part)
+			}
+		}
+		set.ranges = append(set.ranges, [2]int{lo, hi})
+	}
+	return set, nil
+}
+
+// Contains reports whether episode n is selected
+func (s EpisodeSet) Contains(n int) bool {
+	if len(s.ranges) == 0 {
+		return true
+	}
+	for _, r := range s.ranges {
+		if n >= r[0] && n <= r[1] {
+			return true
+		}
+	}
+	return false
+}
+
+func (s EpisodeSet) String() string {
+	if len(s.ranges) == 0 {
+		return "all"
+	}
+	parts := make([]string, len(s.ranges))
+	for i, r := range s.ranges {
+		if r[0] == r[1] {
+			parts[i] = strconv.Itoa(r[0])
+		} else {
+			parts[i] = fmt.Sprintf("%d-%d", r[0], r[1])
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// SelectEpisodes returns per-episode metadata for the chosen season (0 for
+// all seasons) and episodes, in season and episode order
+func (m *Metadata) SelectEpisodes(season int, episodes EpisodeSet) ([]*Metadata, error) {
+	if len(m.Seasons) == 0 {
+		return nil, fmt.Errorf("no seasons found for %q", m.Title)
+	}
+
+	seasons := append([]Season(nil), m.Seasons...)
+	sort.SliceStable(seasons, func(a, b int) bool { return seasons[a].Number < seasons[b].Number })
+
+	var out []*Metadata
+	found := false
+	for _, s := range seasons {
+		if season != 0 && s.Number != season {
+			continue
+		}
+		found = true
+
+		eps := append([]Episode(nil), s.Episodes...)
+		sort.SliceStable(eps, func(a, b int) bool { return eps[a].Number < eps[b].Number })
+		for _, ep := range eps {
+			if episodes.Contains(ep.Number) {
+				out = append(out, m.ForEpisode(s.Number, ep))
+			}
+		}
+	}
+
+	if !found {
+		return nil, fmt.Errorf("season %d not found (available: %s)", season, seasonList(seasons))
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("no episodes match %s", episodes)
+	}
+	return out, nil
+}
+
+func seasonList(seasons []Season) string {
+	parts := make([]string, len(seasons))
+	for i, s := range seasons {
+		parts[i] = fmt.Sprintf("%d (%d episodes)", s.Number, len(s.Episodes))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,137 @@
+package metadata
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// SidecarName is the file every download leaves in its RootDir
+const SidecarName = "maya.json"
+
+// sidecarVersion is bumped whenever the layout changes incompatibly
+const sidecarVersion = 1
+
+// sidecarMu serializes the read-modify-write of sidecars shared by episodes
+// downloaded in parallel
+var sidecarMu sync.Mutex
+
+// Sidecar records what was downloaded into a title folder. Movies have a
+// single entry, series one per episode.
+type Sidecar struct {
+	Version   int             `json:"version"`
+	Downloads []SidecarRecord `json:"downloads"`
+}
+
+// SidecarRecord describes one downloaded media file
+type SidecarRecord struct {
+	File     string     `json:"file"` // relative to RootDir
+	Metadata *Metadata  `json:"metadata"`
+	Source   *Source    `json:"source,omitempty"` // the player source that was picked
+	Stream   StreamInfo `json:"stream"`
+
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+	Size     int64     `json:"size"`
+	SHA256   string    `json:"sha256"`
+}
+
+// StreamInfo is the playlist and variant the media came from
+type StreamInfo struct {
+	URL        string `json:"url"`                  // the requested playlist
+	VariantURL string `json:"variantUrl,omitempty"` // the media playlist picked from a master playlist
+	Bandwidth  int64  `json:"bandwidth,omitempty"`
+	Resolution string `json:"resolution,omitempty"`
+	Codecs     string `json:"codecs,omitempty"`
+}
+
+// ReadSidecar loads the sidecar from dir. A missing file yields an empty sidecar.
+func ReadSidecar(dir string) (*Sidecar, error) {
+	b, err := os.ReadFile(filepath.Join(dir, SidecarName))
+	if errors.Is(err, fs.ErrNotExist) {
+		return &Sidecar{Version: sidecarVersion}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var sc Sidecar
+	if err := json.Unmarshal(b, &sc); err != nil {
+		return nil, fmt.Errorf("%s: %w", SidecarName, err)
+	}
+	if sc.Version > sidecarVersion {
+		return nil, fmt.Errorf("%s: version %d is newer than supported (%d)", SidecarName, sc.Version, sidecarVersion)
+	}
+	return &sc, nil
+}
+
+// RecordDownload measures the finished m.MediaFile and adds rec for it to
+// the sidecar in m.RootDir, replacing an earlier record of the same file
+func RecordDownload(m *Metadata, rec SidecarRecord) error {
+	if m.RootDir == "" || m.MediaFile == "" {
+		return errors.New("sidecar: media paths not built")
+	}
+
+	rel, err := filepath.Rel(m.RootDir, m.MediaFile)
+	if err != nil {
+		return err
+	}
+	rec.File = filepath.ToSlash(rel)
+	rec.Metadata = m
+
+	rec.Size, rec.SHA256, err = checksum(m.MediaFile)
+	if err != nil {
+		return err
+	}
+
+	sidecarMu.Lock()
+	defer sidecarMu.Unlock()
+
+	sc, err := ReadSidecar(m.RootDir)
+	if err != nil {
+		return err
+	}
+
+	replaced := false
+	for i := range sc.Downloads {
+		if sc.Downloads[i].File == rec.File {
+			sc.Downloads[i] = rec
+			replaced = true
+		}
+	}
+	if !replaced {
+		sc.Downloads = append(sc.Downloads, rec)
+	}
+	sc.Version = sidecarVersion
+
+	// write through a temporary file so a crash never leaves half a sidecar
+	tmp := SidecarName + ".part"
+	if err := WriteJSON(m.RootDir, tmp, sc); err != nil {
+		return err
+	}
+	return os.Rename(filepath.Join(m.RootDir, tmp), filepath.Join(m.RootDir, SidecarName))
+}
+
+// checksum returns the size and hex SHA-256 of a file
+func checksum(path string) (int64, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return 0, "", err
+	}
+	return n, hex.EncodeToString(h.Sum(nil)), nil
+}
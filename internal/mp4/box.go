@@ -0,0 +1,44 @@
+package mp4
+
+import "encoding/binary"
+
+// box serialises an ISO BMFF box with the given payload parts
+func box(typ string, parts ...[]byte) []byte {
+	size := 8
+	for _, p := range parts {
+		size += len(p)
+	}
+
+	b := make([]byte, 0, size)
+	b = binary.BigEndian.AppendUint32(b, uint32(size))
+	b = append(b, typ...)
+	for _, p := range parts {
+		b = append(b, p...)
+	}
+	return b
+}
+
+// fullBox serialises a box carrying a version and flags header
+func fullBox(typ string, version uint8, flags uint32, parts ...[]byte) []byte {
+	hdr := []byte{version, byte(flags >> 16), byte(flags >> 8), byte(flags)}
+	return box(typ, append([][]byte{hdr}, parts...)...)
+}
+
+// writer is a tiny big-endian byte builder
+type writer struct {
+	b []byte
+}
+
+func (w *writer) u8(v uint8)   { w.b = append(w.b, v) }
+func (w *writer) u16(v uint16) { w.b = binary.BigEndian.AppendUint16(w.b, v) }
+func (w *writer) u32(v uint32) { w.b = binary.BigEndian.AppendUint32(w.b, v) }
+func (w *writer) u64(v uint64) { w.b = binary.BigEndian.AppendUint64(w.b, v) }
+func (w *writer) raw(b []byte) { w.b = append(w.b, b...) }
+func (w *writer) zeros(n int)  { w.b = append(w.b, make([]byte, n)...) }
+
+// unity transformation matrix used by mvhd and tkhd
+func (w *writer) matrix() {
+	for _, v := range []uint32{0x00010000, 0, 0, 0, 0x00010000, 0, 0, 0, 0x40000000} {
+		w.u32(v)
+	}
+}
@@ -0,0 +1,124 @@
+package mp4
+
+import "github.com/ajaysinghnp/maya-cli/internal/codec"
+
+// visualEntry builds a VisualSampleEntry of the given format around a codec config box
+func visualEntry(format string, width, height int, config []byte) []byte {
+	w := &writer{}
+	w.zeros(6) // reserved
+	w.u16(1)   // data_reference_index
+	w.zeros(16)
+	w.u16(uint16(width))
+	w.u16(uint16(height))
+	w.u32(0x00480000) // 72 dpi
+	w.u32(0x00480000)
+	w.u32(0)
+	w.u16(1)      // frame_count
+	w.zeros(32)   // compressorname
+	w.u16(0x18)   // depth
+	w.u16(0xFFFF) // pre_defined = -1
+	return box(format, w.b, config)
+}
+
+// AVCSampleEntry builds an avc1 sample entry from H.264 parameter sets
+func AVCSampleEntry(sps *codec.H264SPS, spss, ppss [][]byte) []byte {
+	w := &writer{}
+	w.u8(1) // configurationVersion
+	w.u8(sps.Profile)
+	w.u8(sps.Compatibility)
+	w.u8(sps.Level)
+	w.u8(0xFF) // 4 byte NAL lengths
+	w.u8(0xE0 | uint8(len(spss)))
+	for _, p := range spss {
+		w.u16(uint16(len(p)))
+		w.raw(p)
+	}
+	w.u8(uint8(len(ppss)))
+	for _, p := range ppss {
+		w.u16(uint16(len(p)))
+		w.raw(p)
+	}
+
+	return visualEntry("avc1", sps.Width, sps.Height, box("avcC", w.b))
+}
+
+// HEVCSampleEntry builds an hvc1 sample entry from H.265 parameter sets
+func HEVCSampleEntry(sps *codec.H265SPS, vpss, spss, ppss [][]byte) []byte {
+	w := &writer{}
+	w.u8(1) // configurationVersion
+	w.u8(sps.ProfileSpace<<6 | sps.Tier<<5 | sps.Profile)
+	w.u32(sps.Compatibility)
+	w.u16(uint16(sps.Constraints >> 32))
+	w.u32(uint32(sps.Constraints))
+	w.u8(sps.Level)
+	w.u16(0xF000) // min_spatial_segmentation_idc
+	w.u8(0xFC)    // parallelismType
+	w.u8(0xFC | sps.ChromaFormat)
+	w.u8(0xF8 | sps.BitDepthLuma)
+	w.u8(0xF8 | sps.BitDepthChroma)
+	w.u16(0) // avgFrameRate
+
+	nested := uint8(0)
+	if sps.TemporalIDNesting {
+		nested = 1
+	}
+	w.u8(sps.TemporalLayers<<3 | nested<<2 | 3) // 4 byte NAL lengths
+
+	arrays := []struct {
+		typ  uint8
+		nals [][]byte
+	}{
+		{codec.H265NALVPS, vpss},
+		{codec.H265NALSPS, spss},
+		{codec.H265NALPPS, ppss},
+	}
+
+	w.u8(uint8(len(arrays)))
+	for _, a := range arrays {
+		w.u8(0x80 | a.typ) // array_completeness
+		w.u16(uint16(len(a.nals)))
+		for _, n := range a.nals {
+			w.u16(uint16(len(n)))
+			w.raw(n)
+		}
+	}
+
+	return visualEntry("hvc1", sps.Width, sps.Height, box("hvcC", w.b))
+}
+
+// AACSampleEntry builds an mp4a sample entry with an esds descriptor
+func AACSampleEntry(asc []byte, sampleRate, channels int) []byte {
+	w := &writer{}
+	w.zeros(6) // reserved
+	w.u16(1)   // data_reference_index
+	w.zeros(8)
+	w.u16(uint16(channels))
+	w.u16(16) // samplesize
+	w.u32(0)
+	w.u32(uint32(sampleRate) << 16)
+
+	decSpecific := descriptor(0x05, asc)
+
+	dc := &writer{}
+	dc.u8(0x40) // MPEG-4 audio
+	dc.u8(0x15) // audio stream
+	dc.raw([]byte{0, 0, 0})
+	dc.u32(0) // maxBitrate
+	dc.u32(0) // avgBitrate
+	decConfig := descriptor(0x04, append(dc.b, decSpecific...))
+
+	es := &writer{}
+	es.u16(0) // ES_ID
+	es.u8(0)
+	es.raw(decConfig)
+	es.raw(descriptor(0x06, []byte{0x02})) // SLConfig
+
+	return box("mp4a", w.b, fullBox("esds", 0, 0, descriptor(0x03, es.b)))
+}
+
+// descriptor encodes an MPEG-4 descriptor with a 4 byte length field
+func descriptor(tag uint8, payload []byte) []byte {
+	n := len(payload)
+	b := []byte{tag, 0x80 | byte(n>>21&0x7F), 0x80 | byte(n>>14&0x7F), 0x80 | byte(n>>7&0x7F), byte(n & 0x7F)}
+	return append(b, payload...)
+}
@@ -0,0 +1,290 @@
+package mp4
+
+import (
+	"errors"
+	"io"
+)
+
+// movieTimescale is the mvhd/tkhd/elst timescale (milliseconds)
+const movieTimescale = 1000
+
+// Sample is one access unit stored in the media data
+type Sample struct {
+	Offset   int64 // position inside the payload written after the moov box
+	Size     uint32
+	Duration uint32 // in track timescale
+	CTS      uint32 // composition offset (PTS - DTS) in track timescale
+	Sync     bool
+}
+
+// Track is a single video or audio track of a movie
+type Track struct {
+	ID          uint32
+	Handler     string // "vide" or "soun"
+	Timescale   uint32
+	SampleEntry []byte // built with AVCSampleEntry, HEVCSampleEntry or AACSampleEntry
+	Width       int
+	Height      int
+	Samples     []Sample
+
+	// Delay before the track starts, in movie timescale (empty edit)
+	Delay int64
+	// First media time to present, in track timescale (e.g. the initial
+	// composition offset of a video track with B-frames)
+	MediaTime int64
+}
+
+// duration returns the track length in its own timescale
+func (t *Track) duration() int64 {
+	var d int64
+	for _, s := range t.Samples {
+		d += int64(s.Duration)
+	}
+	return d
+}
+
+// WriteMovie writes a progressive "fast start" MP4: ftyp, moov, then one mdat
+// holding payloadSize bytes copied from payload. Sample offsets are relative
+// to the start of the payload.
+func WriteMovie(w io.Writer, tracks []*Track, payload io.Reader, payloadSize int64) error {
+	if len(tracks) == 0 {
+		return errors.New("mp4: no tracks to write")
+	}
+
+	ftyp := box("ftyp", []byte("isom"), []byte{0, 0, 2, 0}, []byte("isomiso2avc1mp41"))
+
+	// the moov size doesn't depend on the chunk offsets (co64 is fixed
+	// width), so build it once to learn where the media data will start
+	moov := buildMoov(tracks, 0)
+	dataStart := int64(len(ftyp)+len(moov)) + 16
+	moov = buildMoov(tracks, dataStart)
+
+	mdat := &writer{}
+	mdat.u32(1) // 64-bit largesize follows
+	mdat.raw([]byte("mdat"))
+	mdat.u64(uint64(16 + payloadSize))
+
+	for _, b := range [][]byte{ftyp, moov, mdat.b} {
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	}
+
+	n, err := io.Copy(w, payload)
+	if err != nil {
+		return err
+	}
+	if n != payloadSize {
+		return errors.New("mp4: media payload size mismatch")
+	}
+	return nil
+}
+
+func buildMoov(tracks []*Track, dataStart int64) []byte {
+	var (
+		traks    [][]byte
+		duration int64
+		nextID   uint32
+	)
+
+	for _, t := range tracks {
+		d := t.Delay + scale(t.duration()-t.MediaTime, t.Timescale, movieTimescale)
+		if d > duration {
+			duration = d
+		}
+		if t.ID >= nextID {
+			nextID = t.ID + 1
+		}
+		traks = append(traks, buildTrak(t, dataStart))
+	}
+
+	mvhd := &writer{}
+	mvhd.u32(0) // creation_time
+	mvhd.u32(0) // modification_time
+	mvhd.u32(movieTimescale)
+	mvhd.u32(uint32(duration))
+	mvhd.u32(0x00010000) // rate 1.0
+	mvhd.u16(0x0100)     // volume 1.0
+	mvhd.zeros(10)
+	mvhd.matrix()
+	mvhd.zeros(24) // pre_defined
+	mvhd.u32(nextID)
+
+	return box("moov", append([][]byte{fullBox("mvhd", 0, 0, mvhd.b)}, traks...)...)
+}
+
+func buildTrak(t *Track, dataStart int64) []byte {
+	mediaDuration := t.duration()
+	presented := scale(mediaDuration-t.MediaTime, t.Timescale, movieTimescale)
+
+	tkhd := &writer{}
+	tkhd.u32(0) // creation_time
+	tkhd.u32(0) // modification_time
+	tkhd.u32(t.ID)
+	tkhd.u32(0)
+	tkhd.u32(uint32(t.Delay + presented))
+	tkhd.zeros(8)
+	tkhd.u16(0) // layer
+	if t.Handler == "soun" {
+		tkhd.u16(1)      // alternate_group: audio tracks replace each other
+		tkhd.u16(0x0100) // volume
+	} else {
+		tkhd.u16(0)
+		tkhd.u16(0)
+	}
+	tkhd.u16(0)
+	tkhd.matrix()
+	tkhd.u32(uint32(t.Width) << 16)
+	tkhd.u32(uint32(t.Height) << 16)
+
+	elst := &writer{}
+	entries := uint32(1)
+	if t.Delay > 0 {
+		entries = 2
+	}
+	elst.u32(entries)
+	if t.Delay > 0 {
+		elst.u32(uint32(t.Delay))
+		elst.u32(0xFFFFFFFF) // empty edit
+		elst.u32(0x00010000)
+	}
+	elst.u32(uint32(presented))
+	elst.u32(uint32(t.MediaTime))
+	elst.u32(0x00010000)
+
+	mdhd := &writer{}
+	mdhd.u32(0)
+	mdhd.u32(0)
+	mdhd.u32(t.Timescale)
+	mdhd.u32(uint32(mediaDuration))
+	mdhd.u16(0x55C4) // "und"
+	mdhd.u16(0)
+
+	hdlr := &writer{}
+	hdlr.u32(0)
+	hdlr.raw([]byte(t.Handler))
+	hdlr.zeros(12)
+	if t.Handler == "vide" {
+		hdlr.raw([]byte("VideoHandler\x00"))
+	} else {
+		hdlr.raw([]byte("SoundHandler\x00"))
+	}
+
+	var mediaHeader []byte
+	if t.Handler == "vide" {
+		mediaHeader = fullBox("vmhd", 0, 1, make([]byte, 8))
+	} else {
+		mediaHeader = fullBox("smhd", 0, 0, make([]byte, 4))
+	}
+
+	dref := fullBox("dref", 0, 0, []byte{0, 0, 0, 1}, fullBox("url ", 0, 1))
+
+	minf := box("minf", mediaHeader, box("dinf", dref), buildStbl(t, dataStart))
+	mdia := box("mdia", fullBox("mdhd", 0, 0, mdhd.b), fullBox("hdlr", 0, 0, hdlr.b), minf)
+
+	return box("trak",
+		fullBox("tkhd", 0, 3, tkhd.b), // enabled | in movie
+		box("edts", fullBox("elst", 0, 0, elst.b)),
+		mdia,
+	)
+}
+
+func buildStbl(t *Track, dataStart int64) []byte {
+	stsd := fullBox("stsd", 0, 0, []byte{0, 0, 0, 1}, t.SampleEntry)
+
+	// stts: run-length encoded durations
+	stts := &writer{}
+	var runs [][2]uint32
+	for _, s := range t.Samples {
+		if n := len(runs); n > 0 && runs[n-1][1] == s.Duration {
+			runs[n-1][0]++
+		} else {
+			runs = append(runs, [2]uint32{1, s.Duration})
+		}
+	}
+	stts.u32(uint32(len(runs)))
+	for _, r := range runs {
+		stts.u32(r[0])
+		stts.u32(r[1])
+	}
+
+	boxes := [][]byte{stsd, fullBox("stts", 0, 0, stts.b)}
+
+	// ctts only when some sample has a composition offset
+	needCTTS := false
+	for _, s := range t.Samples {
+		if s.CTS != 0 {
+			needCTTS = true
+			break
+		}
+	}
+	if needCTTS {
+		var cruns [][2]uint32
+		for _, s := range t.Samples {
+			if n := len(cruns); n > 0 && cruns[n-1][1] == s.CTS {
+				cruns[n-1][0]++
+			} else {
+				cruns = append(cruns, [2]uint32{1, s.CTS})
+			}
+		}
+		ctts := &writer{}
+		ctts.u32(uint32(len(cruns)))
+		for _, r := range cruns {
+			ctts.u32(r[0])
+			ctts.u32(r[1])
+		}
+		boxes = append(boxes, fullBox("ctts", 0, 0, ctts.b))
+	}
+
+	// stss is omitted when every sample is a sync sample
+	var syncs []uint32
+	for i, s := range t.Samples {
+		if s.Sync {
+			syncs = append(syncs, uint32(i+1))
+		}
+	}
+	if len(syncs) != len(t.Samples) {
+		stss := &writer{}
+		stss.u32(uint32(len(syncs)))
+		for _, n := range syncs {
+			stss.u32(n)
+		}
+		boxes = append(boxes, fullBox("stss", 0, 0, stss.b))
+	}
+
+	// one sample per chunk keeps interleaved tracks simple
+	stsc := &writer{}
+	stsc.u32(1)
+	stsc.u32(1) // first_chunk
+	stsc.u32(1) // samples_per_chunk
+	stsc.u32(1) // sample_description_index
+
+	stsz := &writer{}
+	stsz.u32(0)
+	stsz.u32(uint32(len(t.Samples)))
+	for _, s := range t.Samples {
+		stsz.u32(s.Size)
+	}
+
+	co64 := &writer{}
+	co64.u32(uint32(len(t.Samples)))
+	for _, s := range t.Samples {
+		co64.u64(uint64(dataStart + s.Offset))
+	}
+
+	boxes = append(boxes,
+		fullBox("stsc", 0, 0, stsc.b),
+		fullBox("stsz", 0, 0, stsz.b),
+		fullBox("co64", 0, 0, co64.b),
+	)
+
+	return box("stbl", boxes...)
+}
+
+// scale converts v from one timescale to another
+func scale(v int64, from, to uint32) int64 {
+	if from == 0 {
+		return 0
+	}
+	return v * int64(to) / int64(from)
+}
@@ -0,0 +1,150 @@
+package mpegts
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"sort"
+)
+
+// Unit is a complete PES packet of one elementary stream
+type Unit struct {
+	PID        uint16
+	StreamType uint8
+	PES        *PES
+}
+
+// Demuxer reads a transport stream and returns its PES packets
+type Demuxer struct {
+	r *bufio.Reader
+
+	pmtPIDs map[uint16]bool
+	streams map[uint16]uint8
+	pending map[uint16][]byte
+	ready   []Unit
+	eof     bool
+}
+
+// NewDemuxer creates a demuxer reading transport packets from r
+func NewDemuxer(r io.Reader) *Demuxer {
+	return &Demuxer{
+		r:       bufio.NewReaderSize(r, 64*PacketSize),
+		pmtPIDs: map[uint16]bool{},
+		streams: map[uint16]uint8{},
+		pending: map[uint16][]byte{},
+	}
+}
+
+// Streams returns the elementary streams announced so far, keyed by PID
+func (d *Demuxer) Streams() map[uint16]uint8 {
+	return d.streams
+}
+
+// Next returns the next complete PES packet or io.EOF once the stream is drained
+func (d *Demuxer) Next() (Unit, error) {
+	for len(d.ready) == 0 {
+		if d.eof {
+			return Unit{}, io.EOF
+		}
+		if err := d.readPacket(); err != nil {
+			return Unit{}, err
+		}
+	}
+
+	u := d.ready[0]
+	d.ready = d.ready[1:]
+	return u, nil
+}
+
+func (d *Demuxer) readPacket() error {
+	buf := make([]byte, PacketSize)
+
+	if _, err := io.ReadFull(d.r, buf); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			d.finish()
+			return nil
+		}
+		return err
+	}
+
+	// resynchronise after garbage between packets
+	for buf[0] != SyncByte {
+		i := 1
+		for i < len(buf) && buf[i] != SyncByte {
+			i++
+		}
+		copy(buf, buf[i:])
+		if _, err := io.ReadFull(d.r, buf[PacketSize-i:]); err != nil {
+			d.finish()
+			return nil
+		}
+	}
+
+	pkt, err := ParsePacket(buf)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case pkt.PID == 0:
+		if pkt.PayloadStart {
+			if pids, err := ParsePAT(pkt.Payload); err == nil {
+				for _, pid := range pids {
+					d.pmtPIDs[pid] = true
+				}
+			}
+		}
+
+	case d.pmtPIDs[pkt.PID]:
+		if pkt.PayloadStart {
+			if pmt, err := ParsePMT(pkt.Payload); err == nil {
+				for _, es := range pmt.Streams {
+					d.streams[es.PID] = es.Type
+				}
+			}
+		}
+
+	default:
+		if _, ok := d.streams[pkt.PID]; !ok {
+			return nil
+		}
+		if pkt.PayloadStart {
+			d.flush(pkt.PID)
+			d.pending[pkt.PID] = append([]byte(nil), pkt.Payload...)
+		} else if data, ok := d.pending[pkt.PID]; ok {
+			d.pending[pkt.PID] = append(data, pkt.Payload...)
+		}
+	}
+
+	return nil
+}
+
+// flush turns the buffered payload of pid into a ready unit
+func (d *Demuxer) flush(pid uint16) {
+	data, ok := d.pending[pid]
+	if !ok {
+		return
+	}
+	delete(d.pending, pid)
+
+	pes, err := ParsePES(data)
+	if err != nil {
+		return // damaged packet, drop it
+	}
+	d.ready = append(d.ready, Unit{PID: pid, StreamType: d.streams[pid], PES: pes})
+}
+
+// finish flushes every stream at the end of input
+func (d *Demuxer) finish() {
+	d.eof = true
+
+	pids := make([]int, 0, len(d.pending))
+	for pid := range d.pending {
+		pids = append(pids, int(pid))
+	}
+	sort.Ints(pids)
+
+	for _, pid := range pids {
+		d.flush(uint16(pid))
+	}
+}
@@ -0,0 +1,157 @@
+package mpegts
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	PacketSize = 188
+	SyncByte   = 0x47
+
+	maxPayload = PacketSize - 4
+)
+
+// Stream types announced in the PMT
+const (
+	StreamTypeAAC           = 0x0F
+	StreamTypeH264          = 0x1B
+	StreamTypeH265          = 0x24
+	StreamTypeAACSampleAES  = 0xCF
+	StreamTypeH264SampleAES = 0xDB
+)
+
+// Packet is one 188 byte transport stream packet
+type Packet struct {
+	PID          uint16
+	PayloadStart bool // payload_unit_start_indicator
+	Continuity   uint8
+	Adaptation   []byte // adaptation field without its length byte, nil if absent
+	Payload      []byte
+}
+
+// ParsePacket decodes a single transport stream packet
+func ParsePacket(b []byte) (*Packet, error) {
+	if len(b) < PacketSize {
+		return nil, errors.New("mpegts: short packet")
+	}
+	if b[0] != SyncByte {
+		return nil, errors.New("mpegts: lost sync")
+	}
+
+	p := &Packet{
+		PID:          uint16(b[1]&0x1F)<<8 | uint16(b[2]),
+		PayloadStart: b[1]&0x40 != 0,
+		Continuity:   b[3] & 0x0F,
+	}
+
+	control := (b[3] >> 4) & 0x03
+	off := 4
+
+	if control&0x02 != 0 {
+		n := int(b[4])
+		if 5+n > PacketSize {
+			return nil, fmt.Errorf("mpegts: invalid adaptation field length %d", n)
+		}
+		p.Adaptation = b[5 : 5+n]
+		off = 5 + n
+	}
+
+	if control&0x01 != 0 {
+		p.Payload = b[off:PacketSize]
+	}
+
+	return p, nil
+}
+
+// Marshal encodes the packet, padding short payloads with adaptation stuffing
+func (p *Packet) Marshal() ([]byte, error) {
+	af := p.Adaptation
+
+	space := maxPayload
+	if af != nil {
+		space -= 1 + len(af)
+	}
+	if len(p.Payload) > space {
+		return nil, fmt.Errorf("mpegts: payload of %d bytes does not fit", len(p.Payload))
+	}
+
+	if stuffing := space - len(p.Payload); stuffing > 0 {
+		switch {
+		case af != nil:
+			af = append(append([]byte(nil), af...), stuffingBytes(stuffing)...)
+		case stuffing == 1:
+			af = []byte{}
+		default:
+			af = append([]byte{0x00}, stuffingBytes(stuffing-2)...)
+		}
+	}
+
+	b := make([]byte, 0, PacketSize)
+	b = append(b, SyncByte, byte(p.PID>>8)&0x1F, byte(p.PID))
+	if p.PayloadStart {
+		b[1] |= 0x40
+	}
+
+	control := byte(0)
+	if af != nil {
+		control |= 0x02
+	}
+	if len(p.Payload) > 0 {
+		control |= 0x01
+	}
+	b = append(b, control<<4|p.Continuity&0x0F)
+
+	if af != nil {
+		b = append(b, byte(len(af)))
+		b = append(b, af...)
+	}
+	b = append(b, p.Payload...)
+
+	return b, nil
+}
+
+// HasPCR reports whether the adaptation field carries a program clock reference
+func (p *Packet) HasPCR() bool {
+	return len(p.Adaptation) >= 7 && p.Adaptation[0]&0x10 != 0
+}
+
+// Packetize splits a PES packet into transport packets for pid. The optional
+// adaptation field (e.g. one carrying a PCR) is placed in the first packet.
+// cc holds the continuity counter and is advanced for every packet.
+func Packetize(pid uint16, cc *uint8, pes []byte, adaptation []byte) ([]byte, error) {
+	var out []byte
+	first := true
+
+	for first || len(pes) > 0 {
+		p := &Packet{PID: pid, PayloadStart: first, Continuity: *cc}
+
+		space := maxPayload
+		if first && adaptation != nil {
+			p.Adaptation = adaptation
+			space -= 1 + len(adaptation)
+		}
+
+		n := min(space, len(pes))
+		p.Payload, pes = pes[:n], pes[n:]
+
+		b, err := p.Marshal()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, b...)
+
+		*cc = (*cc + 1) & 0x0F
+		first = false
+	}
+
+	return out, nil
+}
+
+func stuffingBytes(n int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = 0xFF
+	}
+	return b
+}
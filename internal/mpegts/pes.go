@@ -0,0 +1,82 @@
+package mpegts
+
+import (
+	"errors"
+)
+
+// PES is a parsed packetized elementary stream packet
+type PES struct {
+	StreamID uint8
+	PTS      int64 // 90 kHz, -1 when absent
+	DTS      int64 // 90 kHz, equals PTS when absent
+	Header   []byte
+	Payload  []byte
+}
+
+// ParsePES splits a complete PES packet into header and payload
+func ParsePES(b []byte) (*PES, error) {
+	if len(b) < 6 || b[0] != 0 || b[1] != 0 || b[2] != 1 {
+		return nil, errors.New("mpegts: missing PES start code")
+	}
+
+	p := &PES{StreamID: b[3], PTS: -1, DTS: -1}
+
+	// stream ids without the optional PES header
+	switch p.StreamID {
+	case 0xBC, 0xBE, 0xBF, 0xF0, 0xF1, 0xF2, 0xF8, 0xFF:
+		p.Header, p.Payload = b[:6], b[6:]
+		return p, nil
+	}
+
+	if len(b) < 9 {
+		return nil, errors.New("mpegts: short PES header")
+	}
+
+	flags := b[7] >> 6
+	hdrLen := 9 + int(b[8])
+	if hdrLen > len(b) {
+		return nil, errors.New("mpegts: truncated PES header")
+	}
+
+	if flags&0x02 != 0 && len(b) >= 14 {
+		p.PTS = readTimestamp(b[9:])
+		p.DTS = p.PTS
+	}
+	if flags == 0x03 && len(b) >= 19 {
+		p.DTS = readTimestamp(b[14:])
+	}
+
+	p.Header, p.Payload = b[:hdrLen], b[hdrLen:]
+
+	if n := int(b[4])<<8 | int(b[5]); n != 0 && 6+n < len(b) {
+		p.Payload = b[hdrLen : 6+n]
+	}
+
+	return p, nil
+}
+
+// Marshal rebuilds the PES packet around a (possibly resized) payload
+func (p *PES) Marshal() []byte {
+	out := make([]byte, 0, len(p.Header)+len(p.Payload))
+	out = append(out, p.Header...)
+	out = append(out, p.Payload...)
+
+	// keep "unbounded" (0) lengths as they were, otherwise update them
+	if prev := int(p.Header[4])<<8 | int(p.Header[5]); prev != 0 {
+		n := len(out) - 6
+		if n > 0xFFFF {
+			n = 0
+		}
+		out[4], out[5] = byte(n>>8), byte(n)
+	}
+
+	return out
+}
+
+func readTimestamp(b []byte) int64 {
+	return int64(b[0]>>1&0x07)<<30 |
+		int64(b[1])<<22 |
+		int64(b[2]>>1)<<15 |
+		int64(b[3])<<7 |
+		int64(b[4]>>1)
+}
@@ -0,0 +1,150 @@
+package mpegts
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ElementaryStream is one entry of a program map table
+type ElementaryStream struct {
+	Type uint8
+	PID  uint16
+}
+
+// PMT is a parsed program map table
+type PMT struct {
+	PCRPID  uint16
+	Streams []ElementaryStream
+}
+
+// section returns the PSI section behind the pointer field of a payload
+// together with the offset of the section within the payload
+func section(payload []byte) ([]byte, int, error) {
+	if len(payload) < 1 {
+		return nil, 0, errors.New("mpegts: empty PSI payload")
+	}
+
+	off := 1 + int(payload[0])
+	if off+3 > len(payload) {
+		return nil, 0, errors.New("mpegts: invalid pointer field")
+	}
+
+	length := int(payload[off+1]&0x0F)<<8 | int(payload[off+2])
+	end := off + 3 + length
+	if length < 9 || end > len(payload) {
+		return nil, 0, fmt.Errorf("mpegts: invalid section length %d", length)
+	}
+
+	return payload[off:end], off, nil
+}
+
+// ParsePAT returns the PMT PIDs listed in a program association table
+func ParsePAT(payload []byte) ([]uint16, error) {
+	sec, _, err := section(payload)
+	if err != nil {
+		return nil, err
+	}
+	if sec[0] != 0x00 {
+		return nil, fmt.Errorf("mpegts: unexpected PAT table id 0x%02x", sec[0])
+	}
+
+	var pids []uint16
+	for i := 8; i+4 <= len(sec)-4; i += 4 {
+		program := uint16(sec[i])<<8 | uint16(sec[i+1])
+		pid := uint16(sec[i+2]&0x1F)<<8 | uint16(sec[i+3])
+		if program != 0 { // program 0 points at the network PID
+			pids = append(pids, pid)
+		}
+	}
+
+	return pids, nil
+}
+
+// ParsePMT parses a program map table
+func ParsePMT(payload []byte) (*PMT, error) {
+	sec, _, err := section(payload)
+	if err != nil {
+		return nil, err
+	}
+	if sec[0] != 0x02 {
+		return nil, fmt.Errorf("mpegts: unexpected PMT table id 0x%02x", sec[0])
+	}
+
+	pmt := &PMT{PCRPID: uint16(sec[8]&0x1F)<<8 | uint16(sec[9])}
+
+	err = eachStream(sec, func(i int) {
+		pmt.Streams = append(pmt.Streams, ElementaryStream{
+			Type: sec[i],
+			PID:  uint16(sec[i+1]&0x1F)<<8 | uint16(sec[i+2]),
+		})
+	})
+
+	return pmt, err
+}
+
+// RemapPMTStreamTypes returns a copy of a PMT payload with stream types
+// replaced according to remap and the section CRC recomputed
+func RemapPMTStreamTypes(payload []byte, remap map[uint8]uint8) ([]byte, error) {
+	out := append([]byte(nil), payload...)
+
+	sec, off, err := section(out)
+	if err != nil {
+		return nil, err
+	}
+
+	err = eachStream(sec, func(i int) {
+		if t, ok := remap[sec[i]]; ok {
+			sec[i] = t
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	crc := CRC32(sec[:len(sec)-4])
+	end := off + len(sec)
+	out[end-4] = byte(crc >> 24)
+	out[end-3] = byte(crc >> 16)
+	out[end-2] = byte(crc >> 8)
+	out[end-1] = byte(crc)
+
+	return out, nil
+}
+
+// eachStream calls fn with the offset of every elementary stream entry in a PMT section
+func eachStream(sec []byte, fn func(i int)) error {
+	if len(sec) < 16 {
+		return errors.New("mpegts: short PMT section")
+	}
+
+	infoLen := int(sec[10]&0x0F)<<8 | int(sec[11])
+	i := 12 + infoLen
+	end := len(sec) - 4 // CRC
+
+	for i+5 <= end {
+		fn(i)
+		esInfoLen := int(sec[i+3]&0x0F)<<8 | int(sec[i+4])
+		i += 5 + esInfoLen
+	}
+
+	if i > end {
+		return errors.New("mpegts: truncated PMT stream loop")
+	}
+	return nil
+}
+
+// CRC32 computes the MPEG-2 CRC used by PSI sections
+func CRC32(b []byte) uint32 {
+	crc := uint32(0xFFFFFFFF)
+	for _, c := range b {
+		crc ^= uint32(c) << 24
+		for i := 0; i < 8; i++ {
+			if crc&0x80000000 != 0 {
+				crc = crc<<1 ^ 0x04C11DB7
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
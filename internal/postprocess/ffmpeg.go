@@ -0,0 +1,231 @@
+package postprocess
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ajaysinghnp/maya-cli/internal/logger/iface"
+	"github.com/ajaysinghnp/maya-cli/internal/metadata"
+	"github.com/ajaysinghnp/maya-cli/utils"
+)
+
+// ErrFFmpegNotFound is returned when no ffmpeg binary is available on PATH
+var ErrFFmpegNotFound = errors.New("ffmpeg not found on PATH")
+
+// Track is an external audio or subtitle file to mux into the output
+type Track struct {
+	Path     string
+	Language string // ISO 639-2 code
+	Title    string
+	Default  bool
+	Forced   bool
+}
+
+// Job describes one post-processing run
+type Job struct {
+	Input     string // the downloaded video (usually MPEG-TS)
+	Output    string // final file, the extension picks the container (.mp4 or .mkv)
+	Audio     []Track
+	Subtitles []Track
+	Meta      *metadata.Metadata // optional, embedded as container tags
+}
+
+// FFmpeg runs post-processing jobs with an ffmpeg binary
+type FFmpeg struct {
+	Path string
+	Log  iface.Logger
+}
+
+// FindFFmpeg looks up ffmpeg on PATH
+func FindFFmpeg(log iface.Logger) (*FFmpeg, error) {
+	path, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return nil, ErrFFmpegNotFound
+	}
+	return &FFmpeg{Path: path, Log: log}, nil
+}
+
+// Run muxes the input and its external tracks into job.Output. The inputs
+// are removed once the output has been written. Cancelling ctx kills ffmpeg
+// and leaves the inputs untouched.
+func (f *FFmpeg) Run(ctx context.Context, job Job) error {
+	format, err := containerFormat(job.Output)
+	if err != nil {
+		return err
+	}
+
+	// ffmpeg can't tell the container from a .part name, so it is passed explicitly
+	tmp := job.Output + ".part"
+	args := buildArgs(job, format, tmp)
+
+	f.Log.Info(fmt.Sprintf("Running ffmpeg: %d audio, %d subtitle tracks -> %s",
+		len(job.Audio), len(job.Subtitles), job.Output))
+	f.Log.Debug(f.Path + " " + strings.Join(args, " "))
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, f.Path, args...)
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		os.Remove(tmp)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			return fmt.Errorf("ffmpeg failed: %w", err)
+		}
+		return fmt.Errorf("ffmpeg failed: %w: %s", err, lastLine(msg))
+	}
+
+	if err := os.Rename(tmp, job.Output); err != nil {
+		return err
+	}
+
+	// everything now lives inside the output container
+	for _, p := range inputs(job) {
+		if p == job.Output {
+			continue
+		}
+		if err := os.Remove(p); err != nil {
+			f.Log.Warn("Failed to remove intermediate file: " + err.Error())
+		}
+	}
+
+	f.Log.Success("Post-processing finished: " + job.Output)
+	return nil
+}
+
+// containerFormat maps an output extension to an ffmpeg muxer name
+func containerFormat(output string) (string, error) {
+	switch ext := strings.ToLower(filepath.Ext(output)); ext {
+	case ".mp4", ".m4v":
+		return "mp4", nil
+	case ".mkv":
+		return "matroska", nil
+	default:
+		return "", fmt.Errorf("unsupported output container %q (use .mp4 or .mkv)", ext)
+	}
+}
+
+// buildArgs assembles the ffmpeg command line: every stream is copied, external
+// tracks get their language and disposition, and the metadata is embedded
+func buildArgs(job Job, format, output string) []string {
+	args := []string{"-hide_banner", "-nostdin", "-loglevel", "error", "-y"}
+
+	for _, p := range inputs(job) {
+		args = append(args, "-i", p)
+	}
+
+	// external audio goes before the video's own audio so the stream indices
+	// used for tagging don't depend on whether the video carries audio at all
+	args = append(args, "-map", "0:v?")
+	for i := range job.Audio {
+		args = append(args, "-map", strconv.Itoa(i+1)+":a")
+	}
+	args = append(args, "-map", "0:a?")
+	for i := range job.Subtitles {
+		args = append(args, "-map", strconv.Itoa(len(job.Audio)+i+1)+":s")
+	}
+
+	args = append(args, "-c", "copy")
+	if format == "mp4" {
+		// MP4 only carries text subtitles as mov_text
+		args = append(args, "-c:s", "mov_text", "-movflags", "+faststart")
+	}
+	for i, t := range job.Audio {
+		args = append(args, trackArgs("a", i, t)...)
+	}
+	for i, t := range job.Subtitles {
+		args = append(args, trackArgs("s", i, t)...)
+	}
+
+	args = append(args, metadataArgs(job.Meta, len(job.Audio))...)
+
+	return append(args, "-f", format, output)
+}
+
+// trackArgs tags one output stream with its language, title and disposition
+func trackArgs(kind string, index int, t Track) []string {
+	spec := fmt.Sprintf("%s:%d", kind, index)
+
+	var args []string
+	if t.Language != "" {
+		args = append(args, "-metadata:s:"+spec, "language="+t.Language)
+	}
+	if t.Title != "" {
+		args = append(args, "-metadata:s:"+spec, "title="+t.Title)
+	}
+
+	var disposition []string
+	if t.Default {
+		disposition = append(disposition, "default")
+	}
+	if t.Forced {
+		disposition = append(disposition, "forced")
+	}
+	if len(disposition) > 0 {
+		args = append(args, "-disposition:"+spec, strings.Join(disposition, "+"))
+	}
+
+	return args
+}
+
+// metadataArgs embeds the title, year and language of the media. ownAudio is
+// the audio stream index the video's muxed-in audio ends up at.
+func metadataArgs(m *metadata.Metadata, ownAudio int) []string {
+	if m == nil {
+		return nil
+	}
+
+	var args []string
+	add := func(key, value string) {
+		if value != "" {
+			args = append(args, "-metadata", key+"="+value)
+		}
+	}
+
+	title := m.Title
+	if m.Type == metadata.Series && m.EpisodeTitle != "" {
+		add("show", m.Title)
+		add("season_number", strconv.Itoa(m.Season))
+		add("episode_sort", strconv.Itoa(m.Episode))
+		title = m.EpisodeTitle
+	}
+	add("title", title)
+	if m.Year > 0 {
+		add("date", strconv.Itoa(m.Year))
+	}
+	add("genre", strings.Join(m.Genres, ", "))
+	if lang := utils.LanguageCode(m.Language); len(lang) == 3 {
+		args = append(args, fmt.Sprintf("-metadata:s:a:%d", ownAudio), "language="+lang)
+	}
+
+	return args
+}
+
+// inputs lists every file fed to ffmpeg in input order
+func inputs(job Job) []string {
+	paths := []string{job.Input}
+	for _, t := range job.Audio {
+		paths = append(paths, t.Path)
+	}
+	for _, t := range job.Subtitles {
+		paths = append(paths, t.Path)
+	}
+	return paths
+}
+
+func lastLine(s string) string {
+	if i := strings.LastIndexByte(s, '\n'); i >= 0 {
+		return s[i+1:]
+	}
+	return s
+}
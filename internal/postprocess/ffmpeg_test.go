@@ -0,0 +1,220 @@
+package postprocess
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ajaysinghnp/maya-cli/internal/metadata"
+)
+
+// stubFFmpeg records its arguments in $FFMPEG_ARGS and, depending on
+// $FFMPEG_STUB, writes the output named by its last argument, fails after
+// starting it, or starts it and hangs until killed
+const stubFFmpeg = `#!/bin/sh
+printf '%s\n' "$@" > "$FFMPEG_ARGS"
+for out; do :; done
+case "$FFMPEG_STUB" in
+ok)   echo muxed > "$out" ;;
+fail) echo partial > "$out"; echo "frame=0" >&2; echo "Invalid data found when processing input" >&2; exit 1 ;;
+hang) echo partial > "$out"; exec sleep 30 ;;
+esac
+`
+
+// installStub puts the stub ffmpeg first on PATH and returns it with the
+// file its arguments are written to
+func installStub(t *testing.T, mode string) (*FFmpeg, string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the ffmpeg stub is a shell script")
+	}
+
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "ffmpeg"), []byte(stubFFmpeg), 0755); err != nil {
+		t.Fatal(err)
+	}
+	argsFile := filepath.Join(bin, "args")
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("FFMPEG_STUB", mode)
+	t.Setenv("FFMPEG_ARGS", argsFile)
+
+	ff, err := FindFFmpeg(nopLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ff, argsFile
+}
+
+// testJob creates a downloaded video with one external audio and subtitle
+// track and returns the job muxing them into output
+func testJob(t *testing.T, output string) Job {
+	t.Helper()
+	dir := t.TempDir()
+	job := Job{
+		Input:     filepath.Join(dir, "Movie.ts"),
+		Output:    filepath.Join(dir, output),
+		Audio:     []Track{{Path: filepath.Join(dir, "Movie.eng.m4a"), Language: "eng", Title: "English"}},
+		Subtitles: []Track{{Path: filepath.Join(dir, "Movie.eng.srt"), Language: "eng", Forced: true}},
+		Meta:      &metadata.Metadata{Title: "Movie", Year: 2024, Language: "Hindi"},
+	}
+	for _, p := range inputs(job) {
+		if err := os.WriteFile(p, []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return job
+}
+
+func TestBuildArgs(t *testing.T) {
+	job := Job{
+		Input: "in.ts",
+		Audio: []Track{
+			{Path: "in.eng.m4a", Language: "eng", Title: "English", Default: true},
+			{Path: "in.tam.m4a", Language: "tam"},
+		},
+		Subtitles: []Track{{Path: "in.eng.srt", Language: "eng", Forced: true}},
+		Meta: &metadata.Metadata{
+			Title: "Show", Type: metadata.Series, Season: 1, Episode: 2, EpisodeTitle: "Pilot",
+			Year: 2024, Genres: []string{"Drama", "Crime"}, Language: "Hindi",
+		},
+	}
+
+	want := []string{
+		"-hide_banner", "-nostdin", "-loglevel", "error", "-y",
+		"-i", "in.ts", "-i", "in.eng.m4a", "-i", "in.tam.m4a", "-i", "in.eng.srt",
+		// external audio first, so the video's own audio is always a:2
+		"-map", "0:v?", "-map", "1:a", "-map", "2:a", "-map", "0:a?", "-map", "3:s",
+		"-c", "copy", "-c:s", "mov_text", "-movflags", "+faststart",
+		"-metadata:s:a:0", "language=eng", "-metadata:s:a:0", "title=English", "-disposition:a:0", "default",
+		"-metadata:s:a:1", "language=tam",
+		"-metadata:s:s:0", "language=eng", "-disposition:s:0", "forced",
+		"-metadata", "show=Show", "-metadata", "season_number=1", "-metadata", "episode_sort=2",
+		"-metadata", "title=Pilot", "-metadata", "date=2024", "-metadata", "genre=Drama, Crime",
+		"-metadata:s:a:2", "language=hin",
+		"-f", "mp4", "out.mp4.part",
+	}
+	if got := buildArgs(job, "mp4", "out.mp4.part"); !reflect.DeepEqual(got, want) {
+		t.Errorf("mp4 args:\n got %q\nwant %q", got, want)
+	}
+
+	// Matroska keeps subtitles as they are
+	mkv := strings.Join(buildArgs(job, "matroska", "out.mkv.part"), " ")
+	if strings.Contains(mkv, "mov_text") || strings.Contains(mkv, "faststart") {
+		t.Errorf("mkv args convert subtitles: %s", mkv)
+	}
+	if !strings.HasSuffix(mkv, "-f matroska out.mkv.part") {
+		t.Errorf("mkv args end in %q", mkv[strings.LastIndex(mkv, "-f"):])
+	}
+}
+
+func TestRun(t *testing.T) {
+	ff, argsFile := installStub(t, "ok")
+	job := testJob(t, "Movie.mp4")
+
+	if err := ff.Run(context.Background(), job); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := os.ReadFile(argsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := buildArgs(job, "mp4", job.Output+".part")
+	if got := strings.Split(strings.TrimSuffix(string(b), "\n"), "\n"); !reflect.DeepEqual(got, want) {
+		t.Errorf("ffmpeg called with\n %q\nwant %q", got, want)
+	}
+
+	if b, err := os.ReadFile(job.Output); err != nil || string(b) != "muxed\n" {
+		t.Errorf("output = %q, %v", b, err)
+	}
+	assertMissing(t, job.Output+".part")
+	for _, p := range inputs(job) {
+		assertMissing(t, p)
+	}
+}
+
+func TestRunFailure(t *testing.T) {
+	ff, _ := installStub(t, "fail")
+	job := testJob(t, "Movie.mkv")
+
+	err := ff.Run(context.Background(), job)
+	if err == nil || !strings.HasSuffix(err.Error(), "Invalid data found when processing input") {
+		t.Fatalf("error = %v, want the last ffmpeg error line", err)
+	}
+
+	assertMissing(t, job.Output+".part")
+	assertMissing(t, job.Output)
+	for _, p := range inputs(job) {
+		assertExists(t, p)
+	}
+}
+
+func TestRunCancelled(t *testing.T) {
+	ff, _ := installStub(t, "hang")
+	job := testJob(t, "Movie.mp4")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		// cancel once ffmpeg has started writing
+		for i := 0; i < 500; i++ {
+			if _, err := os.Stat(job.Output + ".part"); err == nil {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		cancel()
+	}()
+
+	if err := ff.Run(ctx, job); !errors.Is(err, context.Canceled) {
+		t.Fatalf("error = %v, want context.Canceled", err)
+	}
+
+	assertMissing(t, job.Output+".part")
+	assertMissing(t, job.Output)
+	for _, p := range inputs(job) {
+		assertExists(t, p)
+	}
+}
+
+func TestRunUnsupportedContainer(t *testing.T) {
+	ff, argsFile := installStub(t, "ok")
+	job := testJob(t, "Movie.avi")
+
+	err := ff.Run(context.Background(), job)
+	if err == nil || !strings.Contains(err.Error(), `unsupported output container ".avi"`) {
+		t.Fatalf("error = %v", err)
+	}
+	assertMissing(t, argsFile) // ffmpeg never ran
+	for _, p := range inputs(job) {
+		assertExists(t, p)
+	}
+}
+
+func assertMissing(t *testing.T, path string) {
+	t.Helper()
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("%s exists", filepath.Base(path))
+	}
+}
+
+func assertExists(t *testing.T, path string) {
+	t.Helper()
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("%s: %v", filepath.Base(path), err)
+	}
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Info(string)    {}
+func (nopLogger) Debug(string)   {}
+func (nopLogger) Warn(string)    {}
+func (nopLogger) Error(string)   {}
+func (nopLogger) Success(string) {}
@@ -0,0 +1,109 @@
+package progress
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ajaysinghnp/maya-cli/internal/logger/iface"
+)
+
+const (
+	barWidth    = 30
+	redrawEvery = 200 * time.Millisecond
+)
+
+// Bar draws a live progress bar below the log output of an OverlayLogger
+type Bar struct {
+	log   iface.OverlayLogger
+	stats stats
+
+	mu   sync.Mutex
+	stop chan struct{}
+	done chan struct{}
+}
+
+func newBar(log iface.OverlayLogger) *Bar {
+	return &Bar{log: log}
+}
+
+func (b *Bar) Start(label string, total, done int) {
+	b.Finish()
+	b.stats.reset(label, total, done)
+
+	b.mu.Lock()
+	b.stop = make(chan struct{})
+	b.done = make(chan struct{})
+	go b.loop(b.stop, b.done)
+	b.mu.Unlock()
+
+	b.log.SetOverlay(b)
+}
+
+func (b *Bar) Grow(n int)    { b.stats.grow(n) }
+func (b *Bar) Bytes(n int64) { b.stats.addBytes(n) }
+func (b *Bar) SegmentDone()  { b.stats.segmentDone() }
+
+// Finish stops redrawing and replaces the bar with a final log line
+func (b *Bar) Finish() {
+	b.mu.Lock()
+	stop, done := b.stop, b.done
+	b.stop, b.done = nil, nil
+	b.mu.Unlock()
+
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+	b.log.SetOverlay(nil)
+	b.log.Info(message(b.stats.snapshot().final()))
+}
+
+func (b *Bar) loop(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(redrawEvery)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			b.log.Redraw()
+		}
+	}
+}
+
+// Line renders the bar, called by the logger with its lock held
+func (b *Bar) Line() string {
+	s := b.stats.snapshot()
+
+	var bar string
+	if p := s.Percent(); p >= 0 {
+		filled := int(p / 100 * barWidth)
+		if filled > barWidth {
+			filled = barWidth
+		}
+		bar = "[" + strings.Repeat("=", filled)
+		if filled < barWidth {
+			bar += ">" + strings.Repeat(" ", barWidth-filled-1)
+		}
+		bar += "]"
+	} else {
+		// unknown total (live recording): a bouncing marker
+		pos := int(s.Elapsed/redrawEvery) % (2 * (barWidth - 1))
+		if pos >= barWidth {
+			pos = 2*(barWidth-1) - pos
+		}
+		bar = "[" + strings.Repeat(" ", pos) + "*" + strings.Repeat(" ", barWidth-pos-1) + "]"
+	}
+
+	line := fmt.Sprintf("%s %s", bar, s.Summary())
+	if s.Label != "" {
+		line = s.Label + " " + line
+	}
+	return line
+}
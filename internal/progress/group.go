@@ -0,0 +1,51 @@
+package progress
+
+import "sync"
+
+// Group merges transfers running in parallel (episodes of a series) into one
+// reporter: the first Start starts it and later ones add their segments,
+// and it finishes when the last transfer does
+type Group struct {
+	r     Reporter
+	label string
+
+	mu     sync.Mutex
+	active int
+}
+
+// NewGroup returns a group reporting to r under label
+func NewGroup(r Reporter, label string) *Group {
+	return &Group{r: r, label: label}
+}
+
+func (g *Group) Start(_ string, total, done int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.active == 0 {
+		g.r.Start(g.label, total, done)
+	} else {
+		g.r.Grow(total)
+		for i := 0; i < done; i++ {
+			g.r.SegmentDone()
+		}
+	}
+	g.active++
+}
+
+func (g *Group) Grow(n int)    { g.r.Grow(n) }
+func (g *Group) Bytes(n int64) { g.r.Bytes(n) }
+func (g *Group) SegmentDone()  { g.r.SegmentDone() }
+
+func (g *Group) Finish() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.active == 0 {
+		return
+	}
+	g.active--
+	if g.active == 0 {
+		g.r.Finish()
+	}
+}
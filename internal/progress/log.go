@@ -0,0 +1,67 @@
+package progress
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ajaysinghnp/maya-cli/internal/logger/iface"
+)
+
+// logEvery is how often LogReporter prints a progress line
+const logEvery = 10 * time.Second
+
+// LogReporter prints periodic progress lines, for output that isn't a terminal
+type LogReporter struct {
+	log   iface.Logger
+	stats stats
+
+	mu      sync.Mutex
+	started bool
+	lastLog time.Time
+}
+
+func newLogReporter(log iface.Logger) *LogReporter {
+	return &LogReporter{log: log}
+}
+
+func (r *LogReporter) Start(label string, total, done int) {
+	r.stats.reset(label, total, done)
+
+	r.mu.Lock()
+	r.started = true
+	r.lastLog = time.Now()
+	r.mu.Unlock()
+}
+
+func (r *LogReporter) Grow(n int)    { r.stats.grow(n) }
+func (r *LogReporter) Bytes(n int64) { r.stats.addBytes(n) }
+
+func (r *LogReporter) SegmentDone() {
+	r.stats.segmentDone()
+
+	r.mu.Lock()
+	due := time.Since(r.lastLog) >= logEvery
+	if due {
+		r.lastLog = time.Now()
+	}
+	r.mu.Unlock()
+
+	if due {
+		r.print()
+	}
+}
+
+func (r *LogReporter) Finish() {
+	r.mu.Lock()
+	started := r.started
+	r.started = false
+	r.mu.Unlock()
+
+	if started {
+		r.log.Info(message(r.stats.snapshot().final()))
+	}
+}
+
+func (r *LogReporter) print() {
+	r.log.Info(message(r.stats.snapshot()))
+}
@@ -0,0 +1,198 @@
+package progress
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ajaysinghnp/maya-cli/internal/logger/iface"
+)
+
+// Reporter receives download progress events. Implementations must be safe
+// for concurrent use by segment workers.
+type Reporter interface {
+	Start(label string, total, done int) // a new transfer; done counts already finished segments
+	Grow(n int)                          // more segments became known (live streams)
+	Bytes(n int64)                       // n body bytes arrived
+	SegmentDone()                        // one more segment finished
+	Finish()                             // the transfer ended
+}
+
+// New returns a live bar when interactive and log supports overlays, and
+// periodic log lines otherwise
+func New(log iface.Logger, interactive bool) Reporter {
+	if ol, ok := log.(iface.OverlayLogger); ok && interactive {
+		return newBar(ol)
+	}
+	return newLogReporter(log)
+}
+
+// Nop ignores every event
+type Nop struct{}
+
+func (Nop) Start(string, int, int) {}
+func (Nop) Grow(int)               {}
+func (Nop) Bytes(int64)            {}
+func (Nop) SegmentDone()           {}
+func (Nop) Finish()                {}
+
+// Snapshot is the state of a transfer at one point in time
+type Snapshot struct {
+	Label   string
+	Done    int
+	Total   int // 0 when unknown
+	Bytes   int64
+	Speed   float64 // bytes per second
+	ETA     time.Duration
+	Elapsed time.Duration
+}
+
+// Percent returns the finished share of segments, -1 when the total is unknown
+func (s Snapshot) Percent() float64 {
+	if s.Total <= 0 {
+		return -1
+	}
+	return float64(s.Done) / float64(s.Total) * 100
+}
+
+// stats accumulates events and derives speed and ETA
+type stats struct {
+	mu sync.Mutex
+
+	label   string
+	total   int
+	done    int
+	skipped int // segments that were already on disk
+	bytes   int64
+	start   time.Time
+
+	// speed is an exponential moving average sampled at most every speedWindow
+	speed     float64
+	lastBytes int64
+	lastTime  time.Time
+}
+
+const speedWindow = 500 * time.Millisecond
+
+func (s *stats) reset(label string, total, done int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.label, s.total, s.done, s.skipped, s.bytes = label, total, done, done, 0
+	s.start, s.speed, s.lastBytes, s.lastTime = now, 0, 0, now
+}
+
+func (s *stats) grow(n int) {
+	s.mu.Lock()
+	s.total += n
+	s.mu.Unlock()
+}
+
+func (s *stats) addBytes(n int64) {
+	s.mu.Lock()
+	s.bytes += n
+	s.mu.Unlock()
+}
+
+func (s *stats) segmentDone() {
+	s.mu.Lock()
+	s.done++
+	s.mu.Unlock()
+}
+
+func (s *stats) snapshot() Snapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if dt := now.Sub(s.lastTime); dt >= speedWindow {
+		current := float64(s.bytes-s.lastBytes) / dt.Seconds()
+		if s.speed == 0 {
+			s.speed = current
+		} else {
+			s.speed = 0.3*current + 0.7*s.speed
+		}
+		s.lastBytes, s.lastTime = s.bytes, now
+	}
+
+	snap := Snapshot{
+		Label:   s.label,
+		Done:    s.done,
+		Total:   s.total,
+		Bytes:   s.bytes,
+		Speed:   s.speed,
+		Elapsed: now.Sub(s.start),
+	}
+
+	// remaining segments at the average size of the ones fetched in this run
+	if fetched := s.done - s.skipped; fetched > 0 && s.total > s.done && s.speed > 0 {
+		perSegment := float64(s.bytes) / float64(fetched)
+		remaining := perSegment * float64(s.total-s.done)
+		snap.ETA = time.Duration(remaining / s.speed * float64(time.Second))
+	}
+
+	return snap
+}
+
+// Summary formats a snapshot as a single line without any bar
+func (s Snapshot) Summary() string {
+	parts := []string{}
+	if p := s.Percent(); p >= 0 {
+		parts = append(parts, fmt.Sprintf("%3.0f%% (%d/%d segments)", p, s.Done, s.Total))
+	} else {
+		parts = append(parts, fmt.Sprintf("%d segments", s.Done))
+	}
+	parts = append(parts, FormatBytes(s.Bytes))
+	if s.Speed > 0 {
+		parts = append(parts, FormatBytes(int64(s.Speed))+"/s")
+	}
+	if s.ETA > 0 {
+		parts = append(parts, "ETA "+formatDuration(s.ETA))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// final turns a snapshot into the end-of-transfer summary with the average speed
+func (s Snapshot) final() Snapshot {
+	s.ETA = 0
+	if secs := s.Elapsed.Seconds(); secs > 0 {
+		s.Speed = float64(s.Bytes) / secs
+	}
+	return s
+}
+
+// message formats a snapshot for the log
+func message(s Snapshot) string {
+	if s.Label != "" {
+		return s.Label + ": " + s.Summary()
+	}
+	return "Progress: " + s.Summary()
+}
+
+// FormatBytes renders a byte count with binary units, e.g. "12.3 MiB"
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit && exp < 4; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTP"[exp])
+}
+
+// formatDuration renders durations as m:ss or h:mm:ss
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	h := int(d / time.Hour)
+	m := int(d%time.Hour) / int(time.Minute)
+	sec := int(d%time.Minute) / int(time.Second)
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, sec)
+	}
+	return fmt.Sprintf("%d:%02d", m, sec)
+}
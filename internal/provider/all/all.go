@@ -0,0 +1,9 @@
+// Package all registers every built-in provider. Import it for its side
+// effects.
+package all
+
+import (
+	_ "github.com/ajaysinghnp/maya-cli/internal/provider/direct"
+	_ "github.com/ajaysinghnp/maya-cli/internal/provider/moviesbazar"
+	_ "github.com/ajaysinghnp/maya-cli/internal/provider/youtube"
+)
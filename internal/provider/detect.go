@@ -0,0 +1,178 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/ajaysinghnp/maya-cli/internal/httpclient"
+)
+
+// sniffSize is how much of a body is fetched to recognize its content
+const sniffSize = 1024
+
+// Sniffer is implemented by providers that recognize content, for URLs that
+// no provider claims by their address (e.g. playlists behind .txt gateways)
+type Sniffer interface {
+	// Sniff reports whether the provider handles a response with this media
+	// type and first bytes; head is nil when only headers were fetched
+	Sniff(contentType string, head []byte) bool
+}
+
+// Detection explains which provider handles a URL and why
+type Detection struct {
+	URL      *url.URL
+	Provider Provider
+	Sniffed  bool // recognized by its content rather than its address
+
+	// set when the content was fetched
+	ContentType string
+	FinalURL    string // after redirects
+}
+
+// Reason describes how the provider was picked
+func (d *Detection) Reason() string {
+	if !d.Sniffed {
+		return "matched by URL"
+	}
+	if d.ContentType != "" {
+		return "matched by content (" + d.ContentType + ")"
+	}
+	return "matched by content"
+}
+
+// Detect finds the provider for rawURL. URLs that no provider claims by
+// host or path are fetched with client (HEAD, then the first bytes with a
+// ranged GET) and offered to the sniffing providers; a nil client skips that.
+func Detect(ctx context.Context, client *httpclient.Client, rawURL string) (*Detection, error) {
+	u, err := ParseURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if p := match(u); p != nil {
+		return &Detection{URL: u, Provider: p}, nil
+	}
+	if client == nil {
+		return nil, unsupported(rawURL, "")
+	}
+
+	d := &Detection{URL: u, Sniffed: true}
+
+	// headers are enough when the server names a specific type
+	resp, err := send(ctx, client, http.MethodHead, rawURL, nil)
+	if err == nil {
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			d.ContentType, d.FinalURL = mediaType(resp), resp.Request.URL.String()
+			if !generic(d.ContentType) {
+				if p := sniff(d.ContentType, nil); p != nil {
+					d.Provider = p
+					return d, nil
+				}
+			}
+		}
+	}
+
+	resp, err = send(ctx, client, http.MethodGet, rawURL, http.Header{
+		"Range": {fmt.Sprintf("bytes=0-%d", sniffSize-1)},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unsupported URL %s: content check failed: %w", rawURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		return nil, fmt.Errorf("unsupported URL %s: content check failed: HTTP status %d", rawURL, resp.StatusCode)
+	}
+
+	head, err := io.ReadAll(io.LimitReader(resp.Body, sniffSize))
+	if err != nil {
+		return nil, fmt.Errorf("unsupported URL %s: content check failed: %w", rawURL, err)
+	}
+
+	d.ContentType, d.FinalURL = mediaType(resp), resp.Request.URL.String()
+	if d.ContentType == "" {
+		d.ContentType, _, _ = mime.ParseMediaType(http.DetectContentType(head))
+	}
+	if p := sniff(d.ContentType, head); p != nil {
+		d.Provider = p
+		return d, nil
+	}
+	return nil, unsupported(rawURL, d.ContentType)
+}
+
+// match returns the first matching site provider, else the first matching
+// fallback
+func match(u *url.URL) Provider {
+	providers := Providers()
+	for _, fallback := range []bool{false, true} {
+		for _, p := range providers {
+			if isFallback(p) == fallback && p.Match(u) {
+				return p
+			}
+		}
+	}
+	return nil
+}
+
+func sniff(contentType string, head []byte) Provider {
+	for _, p := range Providers() {
+		if s, ok := p.(Sniffer); ok && s.Sniff(contentType, head) {
+			return p
+		}
+	}
+	return nil
+}
+
+// send makes a single attempt; detection should fail fast
+func send(ctx context.Context, client *httpclient.Client, method, rawURL string, header http.Header) (*http.Response, error) {
+	req, err := client.NewRequest(ctx, method, rawURL, header)
+	if err != nil {
+		return nil, err
+	}
+	return client.DoOnce(req)
+}
+
+// mediaType returns the lowercased media type of a response without parameters
+func mediaType(resp *http.Response) string {
+	mt, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(mt)
+}
+
+// generic types say nothing about the content, so the body has to be checked
+func generic(mt string) bool {
+	switch mt {
+	case "", "text/plain", "application/octet-stream", "binary/octet-stream":
+		return true
+	}
+	return false
+}
+
+// HasPrefix reports whether head starts with prefix, ignoring a UTF-8 BOM
+// and leading whitespace
+func HasPrefix(head []byte, prefix string) bool {
+	head = bytes.TrimPrefix(head, []byte("\xef\xbb\xbf"))
+	return bytes.HasPrefix(bytes.TrimLeft(head, " \t\r\n"), []byte(prefix))
+}
+
+func unsupported(rawURL, contentType string) error {
+	providers := Providers()
+	names := make([]string, len(providers))
+	for i, p := range providers {
+		names[i] = p.Name()
+	}
+
+	if contentType != "" {
+		return fmt.Errorf("unsupported URL %s (content type %s, supported: %s)", rawURL, contentType, strings.Join(names, ", "))
+	}
+	return fmt.Errorf("unsupported URL %s (supported: %s)", rawURL, strings.Join(names, ", "))
+}
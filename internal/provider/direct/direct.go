@@ -0,0 +1,60 @@
+package direct
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+
+	"github.com/ajaysinghnp/maya-cli/internal/downloader/m3u8"
+	"github.com/ajaysinghnp/maya-cli/internal/metadata"
+	"github.com/ajaysinghnp/maya-cli/internal/provider"
+)
+
+func init() {
+	provider.Register(New())
+}
+
+// pathRe matches HLS playlist paths, including .m3u
+var pathRe = regexp.MustCompile(`(?i)\.m3u8?$`)
+
+// Provider downloads plain HLS playlist links. There is no page to scrape,
+// so the metadata comes from the user.
+type Provider struct{}
+
+func New() *Provider { return &Provider{} }
+
+func (p *Provider) Name() string { return "m3u8" }
+
+func (p *Provider) Match(u *url.URL) bool {
+	return pathRe.MatchString(u.Path)
+}
+
+// playlistTypes are the media types servers use for HLS playlists
+var playlistTypes = map[string]bool{
+	"application/vnd.apple.mpegurl": true,
+	"application/x-mpegurl":         true,
+	"audio/mpegurl":                 true,
+	"audio/x-mpegurl":               true,
+}
+
+// Sniff recognizes playlists by their media type or #EXTM3U header, for
+// links without a .m3u8 path such as .txt gateways
+func (p *Provider) Sniff(contentType string, head []byte) bool {
+	return playlistTypes[contentType] || provider.HasPrefix(head, "#EXTM3U")
+}
+
+// Fallback lets site providers claim their own playlist URLs first
+func (p *Provider) Fallback() bool { return true }
+
+func (p *Provider) ResolveMetadata(_ context.Context, _ string, env provider.Env) (*metadata.Metadata, error) {
+	env.Log.Info("M3U8 detected → manual metadata input required")
+	return metadata.FromManual(env.Manual, env.Log)
+}
+
+func (p *Provider) ResolveStreams(_ context.Context, rawURL string, _ *metadata.Metadata, _ provider.Env) ([]provider.Stream, error) {
+	return []provider.Stream{{URL: rawURL}}, nil
+}
+
+func (p *Provider) Download(ctx context.Context, job provider.Job, env provider.Env) (*m3u8.Result, error) {
+	return provider.DownloadHLS(ctx, job, env)
+}
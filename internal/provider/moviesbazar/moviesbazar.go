@@ -0,0 +1,68 @@
+package moviesbazar
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+
+	"github.com/ajaysinghnp/maya-cli/internal/downloader/m3u8"
+	"github.com/ajaysinghnp/maya-cli/internal/metadata"
+	"github.com/ajaysinghnp/maya-cli/internal/provider"
+)
+
+func init() {
+	provider.Register(New())
+}
+
+// hostRe matches the site on any of the top-level domains it moves between
+var hostRe = regexp.MustCompile(`(?i)(^|\.)moviesbazar\.[a-z]{2,}(\.[a-z]{2,})?$`)
+
+// Provider scrapes MoviesBazar pages and resolves their embedded player
+type Provider struct {
+	PlayerBase string // scheme and host of the player, DefaultPlayerBase when empty
+}
+
+// New returns the provider for the public site
+func New() *Provider {
+	return &Provider{PlayerBase: DefaultPlayerBase}
+}
+
+func (p *Provider) Name() string { return "moviesbazar" }
+
+func (p *Provider) Match(u *url.URL) bool {
+	return hostRe.MatchString(u.Hostname())
+}
+
+func (p *Provider) ResolveMetadata(ctx context.Context, rawURL string, env provider.Env) (*metadata.Metadata, error) {
+	env.Log.Info("MoviesBazar detected → scraping metadata")
+	return scrapeMetadata(ctx, rawURL, env.Client, env.Log)
+}
+
+// ResolveStreams picks one of the listed sources and follows the player to
+// its playlist
+func (p *Provider) ResolveStreams(ctx context.Context, _ string, meta *metadata.Metadata, env provider.Env) ([]provider.Stream, error) {
+	if len(meta.Sources) == 0 {
+		env.Log.Error("No sources found for this title")
+		return nil, fmt.Errorf("no sources found")
+	}
+
+	i, err := provider.ChooseSource(meta.Sources, env.Source, env.Log)
+	if err != nil {
+		return nil, err
+	}
+	selected := meta.Sources[i]
+	env.Log.Success(fmt.Sprintf("Selected source: %s", selected.Label))
+
+	pl := &player{base: p.PlayerBase, client: env.Client, log: env.Log}
+	stream, err := pl.resolve(ctx, meta, selected)
+	if err != nil {
+		return nil, err
+	}
+	stream.Source = &selected
+	return []provider.Stream{*stream}, nil
+}
+
+func (p *Provider) Download(ctx context.Context, job provider.Job, env provider.Env) (*m3u8.Result, error) {
+	return provider.DownloadHLS(ctx, job, env)
+}
@@ -0,0 +1,194 @@
+package moviesbazar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/ajaysinghnp/maya-cli/internal/httpclient"
+	"github.com/ajaysinghnp/maya-cli/internal/logger/iface"
+	"github.com/ajaysinghnp/maya-cli/internal/metadata"
+	"github.com/ajaysinghnp/maya-cli/internal/provider"
+)
+
+const (
+	// DefaultPlayerBase hosts the embedded player MoviesBazar pages load
+	DefaultPlayerBase = "https://vekna402las.com"
+
+	siteReferer = "https://www.moviesbazar.watch/"
+	firefoxUA   = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10.15; rv:148.0) Gecko/20100101 Firefox/148.0"
+)
+
+var (
+	fileRe = regexp.MustCompile(`"file"\s*:\s*"([^"]+)"`)
+	m3u8Re = regexp.MustCompile(`https?://[^\s'"]+\.m3u8[^\s'"]*`)
+)
+
+// player follows the player page and its playlist gateway to the final
+// M3U8 for a movie or episode
+type player struct {
+	base   string
+	client *httpclient.Client
+	log    iface.Logger
+}
+
+// resolve returns the stream for a movie or episode. Episode sources that
+// already point at an M3U8 are used as they are, and so are movie sources
+// without ids to play. Episodes with only the show's sources always go
+// through the player.
+func (r *player) resolve(ctx context.Context, meta *metadata.Metadata, source metadata.Source) (*provider.Stream, error) {
+	log := r.log
+	base := strings.TrimSuffix(r.base, "/")
+	if base == "" {
+		base = DefaultPlayerBase
+	}
+
+	id := meta.IDs.IMDB
+	if id == "" {
+		id = meta.IDs.TMDB
+	}
+
+	direct := strings.Contains(source.URL, ".m3u8")
+	episode := meta.Type == metadata.Series
+	switch {
+	case episode && direct && !source.Inherited:
+		// episode sources point at their own playlist
+		return &provider.Stream{URL: source.URL, Headers: r.streamHeaders(base)}, nil
+	case episode && id == "":
+		return nil, fmt.Errorf("IMDB/TMDB id missing, cannot resolve S%02dE%02d through the MoviesBazar player", meta.Season, meta.Episode)
+	case id == "" && direct:
+		log.Warn("IMDB/TMDB id missing, using the source URL as it is")
+		return &provider.Stream{URL: source.URL, Headers: r.streamHeaders(base)}, nil
+	case id == "":
+		return nil, errors.New("IMDB/TMDB id missing, cannot resolve MoviesBazar player")
+	}
+
+	// ---------- step 1: player page → playlist gateway ----------
+	playURL := fmt.Sprintf("%s/play/%s", base, url.PathEscape(id))
+	if episode {
+		playURL += fmt.Sprintf("/%d/%d", meta.Season, meta.Episode)
+	}
+	log.Info("Loading player page: " + playURL)
+
+	playHTML, err := httpGet(ctx, r.client, playURL, http.Header{
+		"User-Agent": {firefoxUA},
+		"Referer":    {siteReferer},
+		"Accept":     {"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	fileURL, err := extractP3File(playHTML, playURL)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Success("Resolved playlist gateway")
+	log.Debug("Playlist gateway: " + fileURL)
+
+	// ---------- step 2: gateway (.txt → m3u8) ----------
+	headers := r.streamHeaders(base)
+
+	body, err := httpGet(ctx, r.client, fileURL, headers)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Debug(fmt.Sprintf("Gateway response (%d bytes): %q", len(body), head(body, gatewayPreview)))
+
+	playlistURL := extractM3U8FromText(body, fileURL)
+	if playlistURL == "" {
+		return nil, errors.New("failed to resolve final m3u8 url")
+	}
+
+	log.Success("Final M3U8 resolved")
+	log.Debug("M3U8 URL: " + playlistURL)
+
+	return &provider.Stream{URL: playlistURL, Headers: headers}, nil
+}
+
+// streamHeaders are the headers the player sends for playlist requests
+func (r *player) streamHeaders(base string) http.Header {
+	return http.Header{
+		"User-Agent":      {firefoxUA},
+		"Accept":          {"*/*"},
+		"Accept-Language": {"en-US,en;q=0.9"},
+		"Referer":         {base + "/"},
+		"Origin":          {base},
+		"DNT":             {"1"},
+		"Sec-Fetch-Dest":  {"empty"},
+		"Sec-Fetch-Mode":  {"cors"},
+		"Sec-Fetch-Site":  {"same-site"},
+	}
+}
+
+// extractP3File finds the playlist gateway in the player config, resolved
+// against the page URL
+func extractP3File(html, pageURL string) (string, error) {
+	m := fileRe.FindStringSubmatch(html)
+	if len(m) < 2 {
+		return "", errors.New("p3.file not found in player html")
+	}
+
+	file := strings.ReplaceAll(m[1], `\/`, `/`)
+	return resolveRef(pageURL, file), nil
+}
+
+// extractM3U8FromText returns the playlist behind a gateway response: the
+// gateway itself when it already serves a playlist, else the first M3U8
+// link in the body
+func extractM3U8FromText(body, gatewayURL string) string {
+	if strings.HasPrefix(strings.TrimSpace(body), "#EXTM3U") {
+		return gatewayURL
+	}
+	return m3u8Re.FindString(body)
+}
+
+func resolveRef(base, ref string) string {
+	b, err := url.Parse(base)
+	if err != nil {
+		return ref
+	}
+	u, err := b.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return u.String()
+}
+
+// httpGet fetches a page with the source headers, retrying failures
+func httpGet(ctx context.Context, client *httpclient.Client, url string, headers http.Header) (string, error) {
+	resp, err := client.Get(ctx, url, headers)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("GET %s: unexpected HTTP status: %d", url, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+// gatewayPreview is how much of a gateway response is logged; the rest is
+// usually a long playlist or token soup
+const gatewayPreview = 120
+
+// head returns the first n bytes of s, marked when cut
+func head(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
@@ -0,0 +1,150 @@
+package moviesbazar
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ajaysinghnp/maya-cli/internal/httpclient"
+	"github.com/ajaysinghnp/maya-cli/internal/metadata"
+	"github.com/ajaysinghnp/maya-cli/internal/provider"
+)
+
+// playerServer serves the captured player pages and gateways in testdata the
+// way the player host does, refusing requests without the player's headers
+func playerServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	var srv *httptest.Server
+	serve := func(name string, referer func() string, vars ...string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if got, want := r.Header.Get("Referer"), referer(); got != want {
+				http.Error(w, "bad referer "+got, http.StatusForbidden)
+				return
+			}
+			b, err := os.ReadFile(filepath.Join("testdata", name))
+			if err != nil {
+				t.Error(err)
+				http.NotFound(w, r)
+				return
+			}
+			body := strings.NewReplacer(append([]string{"{{base}}", srv.URL}, vars...)...).Replace(string(b))
+			w.Write([]byte(body))
+		}
+	}
+	site := func() string { return siteReferer }
+	self := func() string { return srv.URL + "/" }
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/play/tt0111161", serve("player_movie.html", site))
+	mux.HandleFunc("/play/tt0944947/2/5", serve("player_episode.html", site))
+	mux.HandleFunc("/play/tt0068646", serve("player_playlist.html", site))
+	mux.HandleFunc("/play/tt0110912", serve("player_nofile.html", site))
+	mux.HandleFunc("/gw/tt0111161/list.txt", serve("gateway.txt", self, "{{id}}", "tt0111161"))
+	mux.HandleFunc("/gw/tt0944947/2/5/list.txt", serve("gateway.txt", self, "{{id}}", "tt0944947/2/5"))
+	mux.HandleFunc("/gw/tt0068646/index.m3u8", serve("gateway.m3u8", self))
+
+	srv = httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestResolveStreams(t *testing.T) {
+	srv := playerServer(t)
+	source := metadata.Source{Label: "Hindi", URL: "https://www.moviesbazar.watch/watch/1"}
+
+	tests := []struct {
+		name    string
+		meta    metadata.Metadata
+		source  *metadata.Source // the listed source when it isn't the page link
+		want    string           // stream URL, without the server address
+		wantErr string
+	}{
+		{
+			name: "movie",
+			meta: metadata.Metadata{Type: metadata.Movie, IDs: metadata.IDs{IMDB: "tt0111161"}},
+			want: "/hls/tt0111161/master.m3u8?token=7f3c9a1e&e=1760745600",
+		},
+		{
+			name: "episode",
+			meta: metadata.Metadata{Type: metadata.Series, IDs: metadata.IDs{IMDB: "tt0944947"}, Season: 2, Episode: 5},
+			want: "/hls/tt0944947/2/5/master.m3u8?token=7f3c9a1e&e=1760745600",
+		},
+		{
+			name: "gateway serves the playlist",
+			meta: metadata.Metadata{Type: metadata.Movie, IDs: metadata.IDs{IMDB: "tt0068646"}},
+			want: "/gw/tt0068646/index.m3u8",
+		},
+		{
+			name:   "episode with its own playlist",
+			meta:   metadata.Metadata{Type: metadata.Series, IDs: metadata.IDs{IMDB: "tt0944947"}, Season: 2, Episode: 5},
+			source: &metadata.Source{Label: "Hindi", URL: "{{base}}/hls/own/2x05.m3u8"},
+			want:   "/hls/own/2x05.m3u8",
+		},
+		{
+			name:   "episode with the show's playlist",
+			meta:   metadata.Metadata{Type: metadata.Series, IDs: metadata.IDs{IMDB: "tt0944947"}, Season: 2, Episode: 5},
+			source: &metadata.Source{Label: "Hindi", URL: "{{base}}/hls/show/master.m3u8", Inherited: true},
+			want:   "/hls/tt0944947/2/5/master.m3u8?token=7f3c9a1e&e=1760745600",
+		},
+		{
+			name:    "episode with the show's playlist and no id",
+			meta:    metadata.Metadata{Type: metadata.Series, Season: 2, Episode: 5},
+			source:  &metadata.Source{Label: "Hindi", URL: "{{base}}/hls/show/master.m3u8", Inherited: true},
+			wantErr: "IMDB/TMDB id missing",
+		},
+		{
+			name:    "player without file",
+			meta:    metadata.Metadata{Type: metadata.Movie, IDs: metadata.IDs{IMDB: "tt0110912"}},
+			wantErr: "p3.file not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			meta := tt.meta
+			meta.Sources = []metadata.Source{source}
+			if tt.source != nil {
+				s := *tt.source
+				s.URL = strings.ReplaceAll(s.URL, "{{base}}", srv.URL)
+				meta.Sources = []metadata.Source{s}
+			}
+
+			p := &Provider{PlayerBase: srv.URL}
+			env := provider.Env{
+				Client: httpclient.Default(),
+				Log:    nopLogger{},
+				Source: provider.SourceSelection{Auto: true},
+			}
+			streams, err := p.ResolveStreams(context.Background(), "", &meta, env)
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := streams[0].URL; got != srv.URL+tt.want {
+				t.Errorf("stream URL = %s, want %s", got, srv.URL+tt.want)
+			}
+			if got := streams[0].Headers.Get("Referer"); got != srv.URL+"/" {
+				t.Errorf("stream Referer = %s, want %s/", got, srv.URL)
+			}
+		})
+	}
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Info(string)    {}
+func (nopLogger) Debug(string)   {}
+func (nopLogger) Warn(string)    {}
+func (nopLogger) Error(string)   {}
+func (nopLogger) Success(string) {}
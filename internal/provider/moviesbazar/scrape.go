@@ -1,6 +1,7 @@
-package resolver
+package moviesbazar
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -11,17 +12,20 @@ import (
 	"github.com/tidwall/gjson"
 
 	"github.com/PuerkitoBio/goquery"
+	"github.com/ajaysinghnp/maya-cli/internal/httpclient"
 	"github.com/ajaysinghnp/maya-cli/internal/logger"
 	"github.com/ajaysinghnp/maya-cli/internal/logger/iface"
 	"github.com/ajaysinghnp/maya-cli/internal/metadata"
 	"github.com/ajaysinghnp/maya-cli/utils"
 )
 
-// fetchMoviesBazarMetadata fetches metadata and m3u8 URLs from a MoviesBazar page
-func fetchMoviesBazarMetadata(url string, log iface.Logger) (*metadata.Metadata, error) {
+// scrapeMetadata fetches metadata and m3u8 URLs from a MoviesBazar page
+func scrapeMetadata(ctx context.Context, url string, client *httpclient.Client, log iface.Logger) (*metadata.Metadata, error) {
 	log.Info("Fetching metadata from MoviesBazar for URL: " + url)
 
-	resp, err := http.Get(url)
+	resp, err := client.Get(ctx, url, http.Header{
+		"Accept": {"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"},
+	})
 	if err != nil {
 		log.Error("Failed to download page: " + err.Error())
 		return nil, err
@@ -51,14 +55,16 @@ func fetchMoviesBazarMetadata(url string, log iface.Logger) (*metadata.Metadata,
 		Title:           scriptData.Title,
 		Year:            utils.NormalizeYear(scriptData.Year),
 		Category:        scriptData.Category,
-		Type:            metadata.Movie,
+		Type:            scriptData.Type,
 		ReleaseDate:     utils.NormalizeDate(scriptData.ReleaseDate),
+		Plot:            strings.TrimSpace(scriptData.Plot),
 		Language:        utils.NormalizeLanguage(scriptData.Language),
 		Genres:          utils.NormalizeSlice(scriptData.Genres),
 		Cast:            utils.NormalizeSlice(scriptData.Cast),
 		Thumbnail:       scriptData.Thumbnail,
 		HlsSourceDomain: scriptData.HlsSourceDomain,
 		Sources:         scriptData.Sources,
+		Seasons:         scriptData.Seasons,
 		IDs: metadata.IDs{
 			IMDB: scriptData.IDs.IMDB,
 			TMDB: scriptData.IDs.TMDB,
@@ -70,6 +76,13 @@ func fetchMoviesBazarMetadata(url string, log iface.Logger) (*metadata.Metadata,
 		meta.Title,
 		meta.Year,
 	))
+	if meta.Type == metadata.Series {
+		episodes := 0
+		for _, s := range meta.Seasons {
+			episodes += len(s.Episodes)
+		}
+		log.Success(fmt.Sprintf("Series with %d season(s), %d episode(s)", len(meta.Seasons), episodes))
+	}
 
 	return meta, nil
 }
@@ -113,6 +126,7 @@ func extractMovieDetailsFromScript(doc *goquery.Document, log iface.Logger) (*me
 				log.Error(fmt.Sprintf("Failed to parse movieDetails: %v", err))
 				return true
 			}
+			normalizeSeasons(parsed.Seasons)
 
 			// Fill nested IDs from root-level fields if present
 			var raw map[string]interface{}
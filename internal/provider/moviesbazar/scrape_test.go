@@ -0,0 +1,63 @@
+package moviesbazar
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/ajaysinghnp/maya-cli/internal/httpclient"
+	"github.com/ajaysinghnp/maya-cli/internal/metadata"
+)
+
+func TestScrapeSeries(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "testdata/series_page.html")
+	}))
+	defer srv.Close()
+
+	meta, err := scrapeMetadata(context.Background(), srv.URL+"/series/the-family-man", httpclient.Default(), nopLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if meta.Title != "The Family Man" || meta.Year != 2019 || meta.Type != metadata.Series {
+		t.Errorf("got %q (%d) of type %q, want \"The Family Man\" (2019) of type series", meta.Title, meta.Year, meta.Type)
+	}
+	if meta.IDs.IMDB != "tt9544034" || meta.IDs.TMDB != "93352" {
+		t.Errorf("IDs = %+v, want tt9544034 and 93352", meta.IDs)
+	}
+	if len(meta.Sources) != 1 {
+		t.Errorf("got %d show sources, want 1", len(meta.Sources))
+	}
+
+	want := []metadata.Season{
+		{Number: 1, Episodes: []metadata.Episode{
+			{
+				Number:    1,
+				Title:     "The Family Man",
+				Plot:      "Srikant and his team nab three suspected terrorists.",
+				Aired:     "2019-09-20",
+				Thumbnail: "https://img.moviesbazar.watch/tfm-1x01.jpg",
+				Sources: []metadata.Source{
+					{Label: "Hindi", URL: "https://vekna402las.com/hls/tt9544034/1/1/master.m3u8", LabelTag: "HD"},
+				},
+			},
+			{Number: 2, Title: "Sleepers", Plot: "Srikant goes undercover.", Aired: "2019-09-20"},
+		}},
+		{Number: 2, Episodes: []metadata.Episode{
+			{
+				Number: 1,
+				Title:  "Exiles",
+				Aired:  "2021-06-04",
+				Sources: []metadata.Source{
+					{Label: "Hindi", URL: "https://vekna402las.com/hls/tt9544034/2/1/master.m3u8"},
+				},
+			},
+		}},
+	}
+	if !reflect.DeepEqual(meta.Seasons, want) {
+		t.Errorf("seasons:\n got %+v\nwant %+v", meta.Seasons, want)
+	}
+}
@@ -0,0 +1,29 @@
+package moviesbazar
+
+import (
+	"strings"
+
+	"github.com/ajaysinghnp/maya-cli/internal/metadata"
+	"github.com/ajaysinghnp/maya-cli/utils"
+)
+
+// normalizeSeasons cleans up the seasons of a series page the way the movie
+// fields are: trimmed text, normalized dates and no sources without a link
+func normalizeSeasons(seasons []metadata.Season) {
+	for i := range seasons {
+		for j := range seasons[i].Episodes {
+			e := &seasons[i].Episodes[j]
+			e.Title = strings.TrimSpace(e.Title)
+			e.Plot = strings.TrimSpace(e.Plot)
+			e.Aired = utils.NormalizeDate(e.Aired)
+
+			var sources []metadata.Source
+			for _, s := range e.Sources {
+				if s.URL != "" {
+					sources = append(sources, s)
+				}
+			}
+			e.Sources = sources
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/ajaysinghnp/maya-cli/internal/downloader/m3u8"
+	"github.com/ajaysinghnp/maya-cli/internal/httpclient"
+	"github.com/ajaysinghnp/maya-cli/internal/logger/iface"
+	"github.com/ajaysinghnp/maya-cli/internal/metadata"
+	"github.com/ajaysinghnp/maya-cli/internal/progress"
+)
+
+// Provider adds support for one site or link type. Implementations live in
+// their own package and call Register from an init function.
+type Provider interface {
+	// Name identifies the provider in logs and errors, e.g. "moviesbazar"
+	Name() string
+
+	// Match reports whether the provider handles u, by host or path
+	Match(u *url.URL) bool
+
+	// ResolveMetadata gathers the title details for rawURL; series pages
+	// return every season in Metadata.Seasons
+	ResolveMetadata(ctx context.Context, rawURL string, env Env) (*metadata.Metadata, error)
+
+	// ResolveStreams returns the playable streams for a movie or a single
+	// episode, best first; the downloader falls back to the next one when a
+	// download fails
+	ResolveStreams(ctx context.Context, rawURL string, meta *metadata.Metadata, env Env) ([]Stream, error)
+
+	// Download fetches a stream into job.Output
+	Download(ctx context.Context, job Job, env Env) (*m3u8.Result, error)
+}
+
+// Env carries the shared client and the user choices into providers
+type Env struct {
+	Client *httpclient.Client
+	Log    iface.Logger
+	Manual metadata.Manual // metadata supplied by the user
+	Source SourceSelection // how to pick between the sources a page lists
+}
+
+// Stream is a resolved playlist and the headers its host expects on every
+// playlist, key and segment request
+type Stream struct {
+	URL     string
+	Headers http.Header
+	Source  *metadata.Source // the listed source it came from, nil for direct links
+}
+
+// Job is one stream to download
+type Job struct {
+	Stream     Stream
+	Output     string
+	TempDir    string
+	Resume     bool
+	Concurrent int
+	Select     m3u8.Selection
+	Live       bool
+	Duration   time.Duration
+	Progress   progress.Reporter
+}
+
+// DownloadHLS is the Download implementation for providers whose streams
+// are HLS playlists
+func DownloadHLS(ctx context.Context, job Job, env Env) (*m3u8.Result, error) {
+	return m3u8.Download(ctx, m3u8.Options{
+		URL:        job.Stream.URL,
+		Output:     job.Output,
+		TempDir:    job.TempDir,
+		Resume:     job.Resume,
+		Concurrent: job.Concurrent,
+		Select:     job.Select,
+		Live:       job.Live,
+		Duration:   job.Duration,
+		Headers:    job.Stream.Headers,
+		Client:     env.Client,
+		Progress:   job.Progress,
+		Log:        env.Log,
+	})
+}
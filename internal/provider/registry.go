@@ -0,0 +1,64 @@
+package provider
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+var (
+	registryMu sync.RWMutex
+	registry   []Provider
+)
+
+// Register makes a provider available to Detect. It panics when a provider
+// with the same name is already registered, like database/sql drivers.
+func Register(p Provider) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	for _, r := range registry {
+		if r.Name() == p.Name() {
+			panic("provider: Register called twice for " + p.Name())
+		}
+	}
+	registry = append(registry, p)
+}
+
+// Providers returns the registered providers in registration order
+func Providers() []Provider {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return append([]Provider(nil), registry...)
+}
+
+// Fallback is implemented by providers that match on any host, such as
+// plain playlist links. Site providers are always tried before them.
+type Fallback interface {
+	Fallback() bool
+}
+
+func isFallback(p Provider) bool {
+	f, ok := p.(Fallback)
+	return ok && f.Fallback()
+}
+
+// ParseURL parses an absolute http(s) URL
+func ParseURL(rawURL string) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL %q: %w", rawURL, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("invalid URL %q: expected an http or https link", rawURL)
+	}
+	return u, nil
+}
+
+// HostMatches reports whether host is domain or one of its subdomains
+func HostMatches(host, domain string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	domain = strings.ToLower(domain)
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
@@ -0,0 +1,190 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/ajaysinghnp/maya-cli/internal/logger/iface"
+	"github.com/ajaysinghnp/maya-cli/internal/metadata"
+	"github.com/ajaysinghnp/maya-cli/utils"
+	"github.com/manifoldco/promptui"
+)
+
+// SourceSelection holds the rules for picking one of the player sources.
+// The zero value prompts for every download.
+type SourceSelection struct {
+	Label      string   // case-insensitive match against Label or LabelTag
+	Index      int      // 1-based position in the source list, 0 = unset
+	PreferLang []string // languages in order of preference, e.g. hin,eng
+	Auto       bool     // never prompt, take the best match (--yes or no terminal)
+}
+
+// ChooseSource applies the selection rules and only prompts when they leave
+// more than one candidate and prompting is allowed
+func ChooseSource(sources []metadata.Source, sel SourceSelection, log iface.Logger) (int, error) {
+	if len(sources) == 0 {
+		return 0, errors.New("no sources found")
+	}
+
+	if sel.Index != 0 {
+		if sel.Index < 1 || sel.Index > len(sources) {
+			return 0, fmt.Errorf("source index %d out of range (1-%d), available: %s",
+				sel.Index, len(sources), listSources(sources, nil))
+		}
+		i := sel.Index - 1
+		if sel.Label != "" && !labelMatches(sources[i], sel.Label) {
+			return 0, fmt.Errorf("source %d is %q, which does not match label %q",
+				sel.Index, sources[i].Label, sel.Label)
+		}
+		return i, nil
+	}
+
+	candidates := make([]int, len(sources))
+	for i := range sources {
+		candidates[i] = i
+	}
+
+	if sel.Label != "" {
+		candidates = filterByLabel(sources, sel.Label)
+		if len(candidates) == 0 {
+			return 0, fmt.Errorf("no source matches label %q, available: %s",
+				sel.Label, listSources(sources, nil))
+		}
+	}
+
+	if len(sel.PreferLang) > 0 {
+		ranked, matched := rankByLanguage(sources, candidates, sel.PreferLang)
+		if !matched {
+			if sel.Auto {
+				return 0, fmt.Errorf("no source in %s, available: %s",
+					strings.Join(sel.PreferLang, ", "), listSources(sources, candidates))
+			}
+			log.Warn(fmt.Sprintf("No source in %s", strings.Join(sel.PreferLang, ", ")))
+		}
+		candidates = ranked
+	}
+
+	if len(candidates) == 1 {
+		return candidates[0], nil
+	}
+
+	if sel.Auto {
+		log.Info(fmt.Sprintf("Picked source %d of %d without prompting", candidates[0]+1, len(sources)))
+		return candidates[0], nil
+	}
+
+	return promptSource(sources, candidates)
+}
+
+// promptSource asks the user to pick one of the candidates, best first
+func promptSource(sources []metadata.Source, candidates []int) (int, error) {
+	items := make([]metadata.Source, len(candidates))
+	for n, i := range candidates {
+		items[n] = sources[i]
+	}
+
+	prompt := promptui.Select{
+		Label: "Select a source (use arrow keys or type number)",
+		Items: items,
+		Templates: &promptui.SelectTemplates{
+			Label:    "{{ . }}?",
+			Active:   "\U000027A4 {{ .Label | cyan }} ({{ .LabelTag | yellow }})",
+			Inactive: "  {{ .Label }} ({{ .LabelTag | faint }})",
+			Selected: "\U00002714 Selected: {{ .Label }}",
+			Details: `
+--------- Sources ----------
+{{ "Label:" | faint }}	{{ .Label }}
+{{ "LabelTag:" | faint }}	{{ .LabelTag }}
+{{ "url:" | faint }}	{{ .URL }}`,
+		},
+		Size: 5,
+	}
+
+	n, _, err := prompt.Run()
+	if err != nil {
+		return 0, err
+	}
+	return candidates[n], nil
+}
+
+// filterByLabel returns the sources whose Label or LabelTag matches label.
+// Exact matches win over substring ones, so "Hindi" doesn't also pick "Hindi (No Ads)".
+func filterByLabel(sources []metadata.Source, label string) []int {
+	var exact, partial []int
+	for i, s := range sources {
+		switch {
+		case strings.EqualFold(s.Label, label) || strings.EqualFold(s.LabelTag, label):
+			exact = append(exact, i)
+		case labelMatches(s, label):
+			partial = append(partial, i)
+		}
+	}
+	if len(exact) > 0 {
+		return exact
+	}
+	return partial
+}
+
+func labelMatches(s metadata.Source, label string) bool {
+	label = strings.ToLower(strings.TrimSpace(label))
+	return strings.Contains(strings.ToLower(s.Label), label) ||
+		strings.Contains(strings.ToLower(s.LabelTag), label)
+}
+
+// rankByLanguage orders candidates by the first preferred language their
+// label mentions; sources naming none keep their place at the end. matched
+// reports whether any candidate named a preferred language.
+func rankByLanguage(sources []metadata.Source, candidates []int, langs []string) (ranked []int, matched bool) {
+	rank := make(map[int]int, len(candidates))
+	for _, i := range candidates {
+		rank[i] = len(langs)
+		for r, lang := range langs {
+			if sourceLanguage(sources[i], lang) {
+				rank[i] = r
+				matched = true
+				break
+			}
+		}
+	}
+
+	ranked = append([]int(nil), candidates...)
+	sort.SliceStable(ranked, func(a, b int) bool {
+		return rank[ranked[a]] < rank[ranked[b]]
+	})
+	return ranked, matched
+}
+
+// sourceLanguage reports whether a word of the Label or LabelTag names lang
+func sourceLanguage(s metadata.Source, lang string) bool {
+	words := strings.FieldsFunc(s.Label+" "+s.LabelTag, func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	for _, w := range words {
+		if utils.SameLanguage(w, lang) {
+			return true
+		}
+	}
+	return false
+}
+
+// listSources formats sources for error messages, only the given indexes when non-nil
+func listSources(sources []metadata.Source, indexes []int) string {
+	if indexes == nil {
+		indexes = make([]int, len(sources))
+		for i := range sources {
+			indexes[i] = i
+		}
+	}
+
+	parts := make([]string, len(indexes))
+	for n, i := range indexes {
+		parts[n] = fmt.Sprintf("%d) %s", i+1, sources[i].Label)
+		if sources[i].LabelTag != "" {
+			parts[n] += " [" + sources[i].LabelTag + "]"
+		}
+	}
+	return strings.Join(parts, ", ")
+}
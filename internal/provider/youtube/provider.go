@@ -0,0 +1,46 @@
+package youtube
+
+import (
+	"context"
+	"errors"
+	"net/url"
+
+	"github.com/ajaysinghnp/maya-cli/internal/downloader/m3u8"
+	"github.com/ajaysinghnp/maya-cli/internal/metadata"
+	"github.com/ajaysinghnp/maya-cli/internal/provider"
+)
+
+func init() {
+	provider.Register(New())
+}
+
+var hosts = []string{"youtube.com", "youtu.be", "youtube-nocookie.com"}
+
+// Provider recognizes YouTube links; downloading is not implemented yet
+type Provider struct{}
+
+func New() *Provider { return &Provider{} }
+
+func (p *Provider) Name() string { return "youtube" }
+
+func (p *Provider) Match(u *url.URL) bool {
+	for _, h := range hosts {
+		if provider.HostMatches(u.Hostname(), h) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Provider) ResolveMetadata(_ context.Context, rawURL string, env provider.Env) (*metadata.Metadata, error) {
+	env.Log.Info("YouTube detected → scraping metadata")
+	return fetchYouTubeMetadata(rawURL, env.Log)
+}
+
+func (p *Provider) ResolveStreams(context.Context, string, *metadata.Metadata, provider.Env) ([]provider.Stream, error) {
+	return nil, errors.New("YouTube downloader not implemented yet")
+}
+
+func (p *Provider) Download(context.Context, provider.Job, provider.Env) (*m3u8.Result, error) {
+	return nil, errors.New("YouTube downloader not implemented yet")
+}
@@ -1,4 +1,4 @@
-package resolver
+package youtube
 
 import (
 	"github.com/ajaysinghnp/maya-cli/internal/logger/iface"
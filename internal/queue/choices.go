@@ -0,0 +1,84 @@
+package queue
+
+import (
+	"github.com/ajaysinghnp/maya-cli/internal/downloader"
+	"github.com/ajaysinghnp/maya-cli/internal/metadata"
+)
+
+// Choices are the selection options given when an item was added. Transfer
+// settings (HTTP, concurrency, progress) come from queue run instead, so
+// they can differ between the day and the night.
+type Choices struct {
+	Output       string   `json:"output,omitempty"`
+	Quality      string   `json:"quality,omitempty"`
+	MaxBandwidth int64    `json:"maxBandwidth,omitempty"`
+	PreferCodec  string   `json:"preferCodec,omitempty"`
+	Audio        []string `json:"audio,omitempty"`
+	Subtitles    []string `json:"subtitles,omitempty"`
+
+	SourceLabel string   `json:"sourceLabel,omitempty"`
+	SourceIndex int      `json:"sourceIndex,omitempty"`
+	PreferLang  []string `json:"preferLang,omitempty"`
+
+	Season   int    `json:"season,omitempty"`
+	Episodes string `json:"episodes,omitempty"` // "1-5,8", empty for all
+
+	NFO         bool   `json:"nfo"`
+	Artwork     bool   `json:"artwork"`
+	Container   string `json:"container,omitempty"`
+	PostProcess string `json:"postprocess,omitempty"`
+}
+
+// ChoicesFrom records the selection part of opts
+func ChoicesFrom(opts downloader.Options) Choices {
+	episodes := opts.Episodes.String()
+	if episodes == "all" {
+		episodes = ""
+	}
+
+	return Choices{
+		Output:       opts.Output,
+		Quality:      opts.Select.Quality,
+		MaxBandwidth: opts.Select.MaxBandwidth,
+		PreferCodec:  opts.Select.PreferCodec,
+		Audio:        opts.Select.Audio,
+		Subtitles:    opts.Select.Subtitles,
+		SourceLabel:  opts.Source.Label,
+		SourceIndex:  opts.Source.Index,
+		PreferLang:   opts.Source.PreferLang,
+		Season:       opts.Season,
+		Episodes:     episodes,
+		NFO:          opts.NFO,
+		Artwork:      opts.Artwork,
+		Container:    opts.Container,
+		PostProcess:  opts.PostProcess,
+	}
+}
+
+// Apply sets the recorded selection on opts. Queued items are downloaded
+// unattended, so sources are always picked without prompting.
+func (c Choices) Apply(opts *downloader.Options) error {
+	episodes, err := metadata.ParseEpisodes(c.Episodes)
+	if err != nil {
+		return err
+	}
+
+	opts.Output = c.Output
+	opts.Select.Quality = c.Quality
+	opts.Select.MaxBandwidth = c.MaxBandwidth
+	opts.Select.PreferCodec = c.PreferCodec
+	opts.Select.Audio = c.Audio
+	opts.Select.Subtitles = c.Subtitles
+	opts.Source.Label = c.SourceLabel
+	opts.Source.Index = c.SourceIndex
+	opts.Source.PreferLang = c.PreferLang
+	opts.Source.Auto = true
+	opts.Meta.Prompt = false
+	opts.Season = c.Season
+	opts.Episodes = episodes
+	opts.NFO = c.NFO
+	opts.Artwork = c.Artwork
+	opts.Container = c.Container
+	opts.PostProcess = c.PostProcess
+	return nil
+}
@@ -0,0 +1,40 @@
+package queue
+
+import (
+	"os"
+	"time"
+)
+
+// Heartbeat timing: a queue run refreshes its running items every
+// heartbeatEvery, so items whose heartbeat is older than heartbeatStale
+// belong to a run that was killed or lost its machine
+const (
+	heartbeatEvery = 15 * time.Second
+	heartbeatStale = 2 * time.Minute
+)
+
+// Owner is the queue run downloading an item
+type Owner struct {
+	Host string `json:"host"`
+	PID  int    `json:"pid"`
+}
+
+// self is the owner recorded by this process
+func self() Owner {
+	host, _ := os.Hostname()
+	return Owner{Host: host, PID: os.Getpid()}
+}
+
+// Abandoned reports whether the item is marked running but its queue run is
+// gone: the run's process no longer exists on this host, or it stopped
+// refreshing the heartbeat
+func (it *Item) Abandoned() bool {
+	if it.Status != Running {
+		return false
+	}
+	if time.Since(it.Heartbeat) > heartbeatStale {
+		return true
+	}
+	me := self()
+	return it.Owner.Host == me.Host && it.Owner.PID != me.PID && !processAlive(it.Owner.PID)
+}
@@ -0,0 +1,17 @@
+//go:build !unix
+
+package queue
+
+import "os"
+
+// processAlive reports whether a process with pid exists. On Windows
+// FindProcess opens the process and fails when there is none; elsewhere the
+// heartbeat alone tells abandoned items apart.
+func processAlive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	p.Release()
+	return true
+}
@@ -0,0 +1,263 @@
+package queue
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/ajaysinghnp/maya-cli/internal/metadata"
+)
+
+// FileName is the queue file inside the maya data dir
+const FileName = "queue.json"
+
+// fileVersion is bumped whenever the layout changes incompatibly
+const fileVersion = 1
+
+// Status is where an item is in its life cycle
+type Status string
+
+const (
+	Queued  Status = "queued"  // waiting for queue run
+	Paused  Status = "paused"  // kept, but skipped by queue run
+	Running Status = "running" // being downloaded
+	Done    Status = "done"
+	Failed  Status = "failed"
+)
+
+// Item is one queued URL with everything needed to download it later
+type Item struct {
+	ID       int                `json:"id"`
+	URL      string             `json:"url"`
+	Provider string             `json:"provider"`
+	Metadata *metadata.Metadata `json:"metadata"`
+	Choices  Choices            `json:"choices"`
+
+	Status   Status    `json:"status"`
+	Error    string    `json:"error,omitempty"`
+	Attempts int       `json:"attempts"`
+	Added    time.Time `json:"added"`
+	Started  time.Time `json:"started,omitzero"`
+	Finished time.Time `json:"finished,omitzero"`
+
+	// set while running: the queue run downloading the item and when it
+	// last reported in
+	Owner     Owner     `json:"owner,omitzero"`
+	Heartbeat time.Time `json:"heartbeat,omitzero"`
+}
+
+// Label returns "Title (Year)", falling back to the URL before metadata is known
+func (it *Item) Label() string {
+	m := it.Metadata
+	switch {
+	case m == nil || m.Title == "":
+		return it.URL
+	case m.Year > 0:
+		return fmt.Sprintf("%s (%d)", m.Title, m.Year)
+	default:
+		return m.Title
+	}
+}
+
+// File is the content of the queue file
+type File struct {
+	Version int     `json:"version"`
+	NextID  int     `json:"nextId"`
+	Items   []*Item `json:"items"`
+}
+
+// Add appends an item, assigning its ID
+func (f *File) Add(it *Item) {
+	if f.NextID < 1 {
+		f.NextID = 1
+	}
+	it.ID = f.NextID
+	f.NextID++
+	f.Items = append(f.Items, it)
+}
+
+// Find returns the item with id, or nil
+func (f *File) Find(id int) *Item {
+	for _, it := range f.Items {
+		if it.ID == id {
+			return it
+		}
+	}
+	return nil
+}
+
+// Remove drops the item with id and reports whether it existed
+func (f *File) Remove(id int) bool {
+	for i, it := range f.Items {
+		if it.ID == id {
+			f.Items = append(f.Items[:i], f.Items[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Next returns the oldest queued item, or nil when nothing is waiting
+func (f *File) Next() *Item {
+	for _, it := range f.Items {
+		if it.Status == Queued {
+			return it
+		}
+	}
+	return nil
+}
+
+// Queue is a queue file shared by every maya process of the user
+type Queue struct {
+	Path string
+}
+
+// Open returns the queue stored at path; the file is created on the first change
+func Open(path string) *Queue {
+	return &Queue{Path: path}
+}
+
+// DefaultPath returns the queue file in the user's data dir:
+// $XDG_DATA_HOME/maya, ~/.local/share/maya, ~/Library/Application Support/maya
+// on macOS or %LOCALAPPDATA%\maya on Windows
+func DefaultPath() (string, error) {
+	dir, err := dataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "maya", FileName), nil
+}
+
+func dataDir() (string, error) {
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return dir, nil
+	}
+
+	switch runtime.GOOS {
+	case "windows":
+		if dir := os.Getenv("LOCALAPPDATA"); dir != "" {
+			return dir, nil
+		}
+		return os.UserConfigDir()
+	case "darwin", "ios":
+		return os.UserConfigDir()
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "share"), nil
+}
+
+// Load reads the queue. A missing file is an empty queue.
+func (q *Queue) Load() (*File, error) {
+	b, err := os.ReadFile(q.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &File{Version: fileVersion, NextID: 1}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var f File
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, fmt.Errorf("invalid queue file %s: %w", q.Path, err)
+	}
+	if f.Version > fileVersion {
+		return nil, fmt.Errorf("queue file %s has version %d, this maya reads up to %d", q.Path, f.Version, fileVersion)
+	}
+	return &f, nil
+}
+
+// Update loads the queue, applies fn and writes the result back while
+// holding the queue lock, so commands running side by side (queue add during
+// queue run) never lose each other's changes. Nothing is written when fn
+// fails.
+func (q *Queue) Update(fn func(f *File) error) error {
+	unlock, err := q.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	f, err := q.Load()
+	if err != nil {
+		return err
+	}
+	if err := fn(f); err != nil {
+		return err
+	}
+	return q.save(f)
+}
+
+// save writes the queue atomically
+func (q *Queue) save(f *File) error {
+	f.Version = fileVersion
+
+	b, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(q.Path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(q.Path), "."+FileName+"-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), q.Path)
+}
+
+// Lock timing: updates take milliseconds, so a lock older than lockStale was
+// left behind by a killed process
+const (
+	lockWait  = 10 * time.Second
+	lockPoll  = 50 * time.Millisecond
+	lockStale = 30 * time.Second
+)
+
+// lock takes the queue lock file and returns the function releasing it
+func (q *Queue) lock() (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(q.Path), 0755); err != nil {
+		return nil, err
+	}
+
+	path := q.Path + ".lock"
+	deadline := time.Now().Add(lockWait)
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			fmt.Fprintf(f, "%d\n", os.Getpid())
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, fmt.Errorf("failed to lock queue: %w", err)
+		}
+
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > lockStale {
+			os.Remove(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("queue is locked by another maya process (remove %s if none is running)", path)
+		}
+		time.Sleep(lockPoll)
+	}
+}
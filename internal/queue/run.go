@@ -0,0 +1,213 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ajaysinghnp/maya-cli/internal/downloader"
+	"github.com/ajaysinghnp/maya-cli/internal/logger/iface"
+	"github.com/ajaysinghnp/maya-cli/internal/progress"
+)
+
+// Summary counts what one queue run did
+type Summary struct {
+	Done        int
+	Failed      int
+	Interrupted int // put back as queued by Ctrl-C
+}
+
+// Runner drains a queue, Jobs items at a time
+type Runner struct {
+	Queue      *Queue
+	Downloader *downloader.Downloader
+	Log        iface.Logger
+	Jobs       int
+}
+
+// Run downloads queued items with base plus each item's choices until none
+// are left. Items added while it runs are picked up, paused and removed ones
+// are skipped. Several runs can share a queue; items abandoned by a run that
+// died are queued again. Cancelling ctx puts the running items back in the
+// queue so the next run resumes them.
+func (r *Runner) Run(ctx context.Context, base downloader.Options) (Summary, error) {
+	var sum Summary
+
+	stop := r.heartbeat()
+	defer stop()
+
+	jobs := r.Jobs
+	if jobs < 1 {
+		jobs = 1
+	}
+	if jobs > 1 && base.Progress != nil {
+		base.Progress = progress.NewGroup(base.Progress, "queue")
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+
+	for w := 0; w < jobs; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				it, err := r.claim()
+				if err == nil && it == nil {
+					return // queue drained
+				}
+
+				var status Status
+				if err == nil {
+					status, err = r.download(ctx, it, base)
+				}
+
+				mu.Lock()
+				if err != nil && firstErr == nil {
+					firstErr = err
+				}
+				switch status {
+				case Done:
+					sum.Done++
+				case Failed:
+					sum.Failed++
+				case Queued:
+					sum.Interrupted++
+				}
+				mu.Unlock()
+
+				// the queue file can't be read or written, stop
+				if err != nil {
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	return sum, firstErr
+}
+
+// heartbeat keeps the items this run downloads marked as alive until the
+// returned function is called
+func (r *Runner) heartbeat() func() {
+	me := self()
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		t := time.NewTicker(heartbeatEvery)
+		defer t.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-t.C:
+			}
+
+			err := r.Queue.Update(func(f *File) error {
+				for _, it := range f.Items {
+					if it.Status == Running && it.Owner == me {
+						it.Heartbeat = time.Now()
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				r.Log.Warn("Failed to refresh queue heartbeat: " + err.Error())
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
+
+// recover puts items abandoned by a queue run that died back in the queue.
+// Items of runs that are still going are left alone.
+func (r *Runner) recover(f *File) {
+	for _, it := range f.Items {
+		if it.Abandoned() {
+			it.Status = Queued
+			it.Owner, it.Heartbeat = Owner{}, time.Time{}
+			r.Log.Warn(fmt.Sprintf("Item %d was left running by an earlier run, queued again", it.ID))
+		}
+	}
+}
+
+// claim marks the next queued item as running by this process and returns a
+// copy of it, or nil when nothing is waiting
+func (r *Runner) claim() (*Item, error) {
+	var claimed *Item
+	err := r.Queue.Update(func(f *File) error {
+		r.recover(f)
+
+		it := f.Next()
+		if it == nil {
+			return nil
+		}
+		it.Status = Running
+		it.Attempts++
+		it.Error = ""
+		it.Started = time.Now()
+		it.Finished = time.Time{}
+		it.Owner = self()
+		it.Heartbeat = it.Started
+
+		c := *it
+		claimed = &c
+		return nil
+	})
+	return claimed, err
+}
+
+// download runs one item and records the outcome. A download failure is
+// recorded in the item; the error is only for a failure to write the queue.
+func (r *Runner) download(ctx context.Context, it *Item, base downloader.Options) (Status, error) {
+	r.Log.Info(fmt.Sprintf("[queue %d] %s (attempt %d): %s", it.ID, it.Label(), it.Attempts, it.URL))
+
+	opts := base
+	err := it.Choices.Apply(&opts)
+	if err == nil {
+		opts.Metadata = it.Metadata
+		err = r.Downloader.StartDownload(ctx, it.URL, opts)
+	}
+
+	status := Done
+	switch {
+	case err != nil && ctx.Err() != nil:
+		status = Queued
+		r.Log.Warn(fmt.Sprintf("[queue %d] Interrupted, queued again", it.ID))
+	case err != nil:
+		status = Failed
+		r.Log.Error(fmt.Sprintf("[queue %d] Download failed: %v", it.ID, err))
+	default:
+		r.Log.Success(fmt.Sprintf("[queue %d] Download completed: %s", it.ID, it.Label()))
+	}
+
+	werr := r.Queue.Update(func(f *File) error {
+		cur := f.Find(it.ID)
+		if cur == nil || cur.Owner != it.Owner {
+			return nil // removed, or taken over after missing heartbeats
+		}
+		cur.Status = status
+		cur.Finished = time.Now()
+		cur.Owner, cur.Heartbeat = Owner{}, time.Time{}
+		if status == Failed {
+			cur.Error = err.Error()
+		}
+		return nil
+	})
+	if werr != nil {
+		return "", fmt.Errorf("failed to update queue: %w", werr)
+	}
+	return status, nil
+}
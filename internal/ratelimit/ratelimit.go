@@ -0,0 +1,142 @@
+package ratelimit
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// minChunk keeps reads from being split into uselessly small pieces at low rates
+const minChunk = 16 * 1024
+
+// Limiter is a token bucket shared by every reader drawing from it, so the
+// rate is an aggregate across all concurrent downloads
+type Limiter struct {
+	rate  float64 // bytes per second
+	burst float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// New returns a limiter allowing bytesPerSec, or nil (no limit) when it is <= 0
+func New(bytesPerSec int64) *Limiter {
+	if bytesPerSec <= 0 {
+		return nil
+	}
+
+	// an eighth of a second worth of data keeps the output smooth
+	burst := float64(bytesPerSec) / 8
+	if burst < minChunk {
+		burst = minChunk
+	}
+
+	return &Limiter{
+		rate:   float64(bytesPerSec),
+		burst:  burst,
+		tokens: burst,
+		last:   time.Now(),
+	}
+}
+
+// Rate returns the limit in bytes per second
+func (l *Limiter) Rate() int64 {
+	if l == nil {
+		return 0
+	}
+	return int64(l.rate)
+}
+
+// Wait blocks until n bytes may pass. Callers take their share up front and
+// sleep off the debt, which keeps concurrent readers fair without a queue.
+func (l *Limiter) Wait(ctx context.Context, n int) error {
+	if l == nil || n <= 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+	l.tokens -= float64(n)
+	debt := l.tokens
+	l.mu.Unlock()
+
+	if debt >= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(time.Duration(-debt / l.rate * float64(time.Second)))
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// chunk is the largest read that should be charged at once
+func (l *Limiter) chunk() int {
+	return int(l.burst)
+}
+
+// Reader returns r throttled by every non-nil limiter, e.g. a global and a
+// per-host one
+func Reader(ctx context.Context, r io.Reader, limiters ...*Limiter) io.Reader {
+	active := nonNil(limiters)
+	if len(active) == 0 {
+		return r
+	}
+	return &reader{ctx: ctx, r: r, limiters: active}
+}
+
+type reader struct {
+	ctx      context.Context
+	r        io.Reader
+	limiters []*Limiter
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	for _, l := range r.limiters {
+		if c := l.chunk(); len(p) > c {
+			p = p[:c]
+		}
+	}
+
+	n, err := r.r.Read(p)
+	for _, l := range r.limiters {
+		if werr := l.Wait(r.ctx, n); werr != nil && err == nil {
+			err = werr
+		}
+	}
+	return n, err
+}
+
+// ReadCloser is Reader for response bodies
+func ReadCloser(ctx context.Context, rc io.ReadCloser, limiters ...*Limiter) io.ReadCloser {
+	active := nonNil(limiters)
+	if len(active) == 0 {
+		return rc
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{&reader{ctx: ctx, r: rc, limiters: active}, rc}
+}
+
+func nonNil(limiters []*Limiter) []*Limiter {
+	var active []*Limiter
+	for _, l := range limiters {
+		if l != nil {
+			active = append(active, l)
+		}
+	}
+	return active
+}
@@ -0,0 +1,164 @@
+package remux
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/ajaysinghnp/maya-cli/internal/logger/iface"
+	"github.com/ajaysinghnp/maya-cli/internal/mp4"
+	"github.com/ajaysinghnp/maya-cli/internal/mpegts"
+)
+
+// TSToMP4 remuxes an MPEG-TS file with H.264/H.265 video and AAC audio into
+// a progressive MP4 without re-encoding. Other streams are dropped.
+func TSToMP4(src, dst string, log iface.Logger) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	// sample data is staged next to the destination so the moov box can be
+	// written in front of it ("fast start")
+	payloadPath := dst + ".mdat"
+	payload, err := os.Create(payloadPath)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(payloadPath)
+	defer payload.Close()
+
+	var (
+		w       = bufio.NewWriterSize(payload, 1<<20)
+		offset  int64
+		tracks  = map[uint16]*trackBuilder{}
+		skipped = map[uint16]bool{}
+		demux   = mpegts.NewDemuxer(in)
+	)
+
+	for {
+		u, err := demux.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		tb := tracks[u.PID]
+		if tb == nil {
+			switch u.StreamType {
+			case mpegts.StreamTypeH264, mpegts.StreamTypeH265, mpegts.StreamTypeAAC:
+				tb = newTrackBuilder(u.PID, u.StreamType)
+				tracks[u.PID] = tb
+			default:
+				if !skipped[u.PID] {
+					skipped[u.PID] = true
+					log.Warn(fmt.Sprintf("Skipping unsupported stream type 0x%02x (PID %d)", u.StreamType, u.PID))
+				}
+				continue
+			}
+		}
+
+		samples, err := tb.push(u.PES)
+		if err != nil {
+			return fmt.Errorf("stream %d: %w", u.PID, err)
+		}
+
+		for _, s := range samples {
+			if _, err := w.Write(s.data); err != nil {
+				return err
+			}
+			tb.record(s, offset)
+			offset += int64(len(s.data))
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	movie, err := buildTracks(tracks, log)
+	if err != nil {
+		return err
+	}
+
+	if _, err := payload.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	tmp := dst + ".part"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriterSize(out, 1<<20)
+	if err := mp4.WriteMovie(bw, movie, payload, offset); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	return os.Rename(tmp, dst)
+}
+
+// buildTracks finalises the track builders, video first, and aligns their
+// start times with edit lists
+func buildTracks(builders map[uint16]*trackBuilder, log iface.Logger) ([]*mp4.Track, error) {
+	var list []*trackBuilder
+	for _, tb := range builders {
+		if len(tb.samples) == 0 {
+			log.Warn(fmt.Sprintf("Stream PID %d has no usable samples, skipping", tb.pid))
+			continue
+		}
+		list = append(list, tb)
+	}
+	if len(list) == 0 {
+		return nil, errors.New("no H.264, H.265 or AAC streams found")
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].isVideo() != list[j].isVideo() {
+			return list[i].isVideo()
+		}
+		return list[i].pid < list[j].pid
+	})
+
+	start := list[0].start()
+	for _, tb := range list {
+		if s := tb.start(); s < start {
+			start = s
+		}
+	}
+
+	var tracks []*mp4.Track
+	for i, tb := range list {
+		if tb.paramsChanged {
+			log.Warn(fmt.Sprintf("Stream PID %d changes parameter sets mid-stream, playback may glitch", tb.pid))
+		}
+
+		t, err := tb.track()
+		if err != nil {
+			return nil, err
+		}
+		t.ID = uint32(i + 1)
+		t.Delay = int64((tb.start() - start) * 1000)
+		tracks = append(tracks, t)
+	}
+
+	return tracks, nil
+}
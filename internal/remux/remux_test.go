@@ -0,0 +1,439 @@
+package remux
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/ajaysinghnp/maya-cli/internal/codec"
+	"github.com/ajaysinghnp/maya-cli/internal/mpegts"
+)
+
+var update = flag.Bool("update", false, "rewrite testdata/h264_aac.ts")
+
+const fixture = "testdata/h264_aac.ts"
+
+// testdata/h264_aac.ts holds 64 frames of 1280x720 H.264 at 25 fps with
+// composition offsets, in two 32 frame segments that each start with an IDR
+// and repeat the PAT/PMT, and 120 frames of 48 kHz stereo AAC in PES packets
+// of 15. Its timestamps start just before the 33 bit wrap: the video PTS
+// wraps at frame 8, the DTS at frame 10 and the audio at its second PES.
+const (
+	fixtureDTS    = tsWrap - 10*frameTicks
+	frameTicks    = tsClock / 25
+	ptsDelay      = 2 * frameTicks
+	videoFrames   = 64
+	gopFrames     = 32
+	aacPerPES     = 15
+	aacFrames     = videoFrames / 8 * aacPerPES
+	aacRate       = 48000
+	aacFrameBytes = 30
+
+	videoPID   = 0x100
+	audioPID   = 0x101
+	privatePID = 0x102
+	pmtPID     = 0x1000
+)
+
+var (
+	fixtureSPS = mustHex("6742c01fda014016e4") // Baseline 3.1, 1280x720
+	fixturePPS = mustHex("68ce3880")
+)
+
+func mustHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// videoSampleSize is the size of frame i in the MP4: one length prefixed
+// slice, with the AUD and parameter sets left out
+func videoSampleSize(i int) uint32 {
+	return uint32(4 + 1 + 200 + i)
+}
+
+// writeFixture generates testdata/h264_aac.ts (go test -run TestTSToMP4 -update)
+func writeFixture(t *testing.T) {
+	t.Helper()
+
+	var (
+		ts                 []byte
+		ccVideo, ccAudio   uint8
+		ccPrivate, ccTable uint8
+	)
+	packetize := func(pid uint16, cc *uint8, pes []byte) {
+		b, err := mpegts.Packetize(pid, cc, pes, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ts = append(ts, b...)
+	}
+
+	for i := 0; i < videoFrames; i++ {
+		dts := fixtureDTS + int64(i)*frameTicks
+
+		if i%gopFrames == 0 {
+			packetize(0, &ccTable, psiSection(0x00, []byte{0, 1, 0xC1, 0, 0, 0, 1, 0xE0 | pmtPID>>8, pmtPID & 0xFF}))
+			packetize(pmtPID, &ccTable, psiSection(0x02, []byte{0, 1, 0xC1, 0, 0,
+				0xE0 | videoPID>>8, videoPID & 0xFF, 0xF0, 0,
+				mpegts.StreamTypeH264, 0xE0 | videoPID>>8, videoPID & 0xFF, 0xF0, 0,
+				mpegts.StreamTypeAAC, 0xE0 | audioPID>>8, audioPID & 0xFF, 0xF0, 0,
+				0x06, 0xE0 | privatePID>>8, privatePID & 0xFF, 0xF0, 0,
+			}))
+			packetize(privatePID, &ccPrivate, pesPacket(0xBD, dts, -1, []byte("private data")))
+		}
+
+		// one audio PES in front of every eighth video frame
+		if i%8 == 0 {
+			var es []byte
+			for f := 0; f < aacPerPES; f++ {
+				es = append(es, adtsFrame(bytes.Repeat([]byte{byte(f)}, aacFrameBytes))...)
+			}
+			packetize(audioPID, &ccAudio, pesPacket(0xC0, dts+ptsDelay, -1, es))
+		}
+
+		nals := [][]byte{{codec.H264NALAUD, 0xF0}}
+		slice := byte(0x41)
+		if i%gopFrames == 0 {
+			nals = append(nals, fixtureSPS, fixturePPS)
+			slice = 0x65
+		}
+		nals = append(nals, append([]byte{slice}, bytes.Repeat([]byte{0x80 | byte(i)}, 200+i)...))
+		packetize(videoPID, &ccVideo, pesPacket(0xE0, dts+ptsDelay, dts, codec.JoinAnnexB(nals)))
+	}
+
+	if err := os.WriteFile(fixture, ts, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// psiSection returns the pointer field and a PAT or PMT section around body
+func psiSection(tableID byte, body []byte) []byte {
+	sec := append([]byte{tableID, 0, 0}, body...)
+	n := len(sec) - 3 + 4 // + CRC
+	sec[1], sec[2] = 0xB0|byte(n>>8), byte(n)
+	crc := mpegts.CRC32(sec)
+	return append(append([]byte{0}, sec...), byte(crc>>24), byte(crc>>16), byte(crc>>8), byte(crc))
+}
+
+// pesPacket builds a PES packet; timestamps are wrapped to 33 bits and the
+// DTS is left out when negative. Video uses the unbounded length.
+func pesPacket(streamID byte, pts, dts int64, es []byte) []byte {
+	timestamp := func(marker byte, v int64) []byte {
+		v &= tsWrap - 1
+		return []byte{marker<<4 | byte(v>>29)&0x0E | 1, byte(v >> 22), byte(v>>14) | 1, byte(v >> 7), byte(v<<1) | 1}
+	}
+
+	h := []byte{0, 0, 1, streamID, 0, 0, 0x80, 0x80, 5}
+	if dts < 0 {
+		h = append(h, timestamp(2, pts)...)
+	} else {
+		h[7], h[8] = 0xC0, 10
+		h = append(append(h, timestamp(3, pts)...), timestamp(1, dts)...)
+	}
+	if streamID != 0xE0 {
+		n := len(h) - 6 + len(es)
+		h[4], h[5] = byte(n>>8), byte(n)
+	}
+	return append(h, es...)
+}
+
+// adtsFrame returns an ADTS frame (AAC LC, 48 kHz, stereo) around body
+func adtsFrame(body []byte) []byte {
+	n := 7 + len(body)
+	h := []byte{0xFF, 0xF1, 0x4C, 0x80 | byte(n>>11), byte(n >> 3), byte(n<<5) | 0x1F, 0xFC}
+	return append(h, body...)
+}
+
+// mp4Box is one box of the remuxed file
+type mp4Box struct {
+	typ  string
+	body []byte
+}
+
+// parseBoxes splits b into boxes, following 64-bit largesizes
+func parseBoxes(t *testing.T, b []byte) []mp4Box {
+	t.Helper()
+	var boxes []mp4Box
+	for len(b) > 0 {
+		if len(b) < 8 {
+			t.Fatalf("%d trailing bytes", len(b))
+		}
+		size, hdr := uint64(binary.BigEndian.Uint32(b)), uint64(8)
+		if size == 1 {
+			size, hdr = binary.BigEndian.Uint64(b[8:]), 16
+		}
+		if size < hdr || size > uint64(len(b)) {
+			t.Fatalf("%q box size %d out of range", b[4:8], size)
+		}
+		boxes = append(boxes, mp4Box{typ: string(b[4:8]), body: b[hdr:size]})
+		b = b[size:]
+	}
+	return boxes
+}
+
+// child returns the body of the first box along path below b
+func child(t *testing.T, b []byte, path ...string) []byte {
+	t.Helper()
+next:
+	for _, typ := range path {
+		for _, bx := range parseBoxes(t, b) {
+			if bx.typ == typ {
+				b = bx.body
+				continue next
+			}
+		}
+		t.Fatalf("no %s box", typ)
+	}
+	return b
+}
+
+// u32s reads the entries of a full box holding a count and n fields per entry
+func u32s(body []byte, skip, fields int) [][]uint32 {
+	body = body[4+skip:] // version and flags
+	entries := make([][]uint32, binary.BigEndian.Uint32(body))
+	for i := range entries {
+		for f := 0; f < fields; f++ {
+			entries[i] = append(entries[i], binary.BigEndian.Uint32(body[4+(i*fields+f)*4:]))
+		}
+	}
+	return entries
+}
+
+func TestTSToMP4(t *testing.T) {
+	if *update {
+		writeFixture(t)
+	}
+
+	dst := filepath.Join(t.TempDir(), "out.mp4")
+	if err := TSToMP4(fixture, dst, nopLogger{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, tmp := range []string{dst + ".part", dst + ".mdat"} {
+		if _, err := os.Stat(tmp); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("%s left behind", filepath.Base(tmp))
+		}
+	}
+
+	file, err := os.ReadFile(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var layout []string
+	var moov, mdat []byte
+	for _, bx := range parseBoxes(t, file) {
+		layout = append(layout, bx.typ)
+		switch bx.typ {
+		case "moov":
+			moov = bx.body
+		case "mdat":
+			mdat = bx.body
+		}
+	}
+	if want := []string{"ftyp", "moov", "mdat"}; !reflect.DeepEqual(layout, want) {
+		t.Fatalf("top level boxes = %v, want %v", layout, want)
+	}
+	mdatStart := uint64(len(file) - len(mdat))
+
+	var traks [][]byte
+	for _, bx := range parseBoxes(t, moov) {
+		if bx.typ == "trak" {
+			traks = append(traks, bx.body)
+		}
+	}
+	if len(traks) != 2 {
+		t.Fatalf("got %d tracks, want video and audio only", len(traks))
+	}
+
+	tests := []struct {
+		name      string
+		handler   string
+		timescale uint32
+		samples   int
+		duration  uint32   // every sample's
+		cts       uint32   // every sample's, 0 for no ctts
+		sync      []uint32 // nil when every sample is a sync sample
+		size      func(i int) uint32
+		first     []byte // start of the first sample
+	}{
+		{
+			name:      "video",
+			handler:   "vide",
+			timescale: tsClock,
+			samples:   videoFrames,
+			duration:  frameTicks,
+			cts:       ptsDelay,
+			sync:      []uint32{1, gopFrames + 1},
+			size:      videoSampleSize,
+			first:     []byte{0, 0, 0, 201, 0x65, 0x80},
+		},
+		{
+			name:      "audio",
+			handler:   "soun",
+			timescale: aacRate,
+			samples:   aacFrames,
+			duration:  aacSamples,
+			size:      func(int) uint32 { return aacFrameBytes },
+			first:     bytes.Repeat([]byte{0}, aacFrameBytes),
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trak := traks[i]
+			if h := child(t, trak, "mdia", "hdlr"); string(h[8:12]) != tt.handler {
+				t.Fatalf("handler = %q, want %q", h[8:12], tt.handler)
+			}
+			mdhd := child(t, trak, "mdia", "mdhd")
+			if ts := binary.BigEndian.Uint32(mdhd[12:]); ts != tt.timescale {
+				t.Errorf("timescale = %d, want %d", ts, tt.timescale)
+			}
+			if d := binary.BigEndian.Uint32(mdhd[16:]); d != uint32(tt.samples)*tt.duration {
+				t.Errorf("media duration = %d, want %d", d, uint32(tt.samples)*tt.duration)
+			}
+
+			// the edit starts at the first sample's presentation time
+			elst := u32s(child(t, trak, "edts", "elst"), 0, 3)
+			if mediaTime := elst[len(elst)-1][1]; mediaTime != tt.cts {
+				t.Errorf("edit media time = %d, want %d", mediaTime, tt.cts)
+			}
+
+			stbl := child(t, trak, "mdia", "minf", "stbl")
+
+			// a single run of equal durations: decode timestamps run from 0
+			// to (n-1) * duration without a jump where the input wrapped
+			stts := u32s(child(t, stbl, "stts"), 0, 2)
+			if want := [][]uint32{{uint32(tt.samples), tt.duration}}; !reflect.DeepEqual(stts, want) {
+				t.Errorf("stts = %v, want %v", stts, want)
+			}
+
+			var ctts [][]uint32
+			for _, bx := range parseBoxes(t, stbl) {
+				if bx.typ == "ctts" {
+					ctts = u32s(bx.body, 0, 2)
+				}
+			}
+			if tt.cts == 0 && ctts != nil {
+				t.Errorf("ctts = %v, want none", ctts)
+			}
+			if want := [][]uint32{{uint32(tt.samples), tt.cts}}; tt.cts != 0 && !reflect.DeepEqual(ctts, want) {
+				t.Errorf("ctts = %v, want %v", ctts, want)
+			}
+
+			var sync []uint32
+			for _, bx := range parseBoxes(t, stbl) {
+				if bx.typ == "stss" {
+					for _, e := range u32s(bx.body, 0, 1) {
+						sync = append(sync, e[0])
+					}
+				}
+			}
+			if !reflect.DeepEqual(sync, tt.sync) {
+				t.Errorf("sync samples = %v, want %v", sync, tt.sync)
+			}
+
+			stsz := u32s(child(t, stbl, "stsz"), 4, 1)
+			co64 := child(t, stbl, "co64")[4:]
+			if len(stsz) != tt.samples {
+				t.Fatalf("stsz has %d entries, want %d", len(stsz), tt.samples)
+			}
+			if n := binary.BigEndian.Uint32(co64); int(n) != tt.samples {
+				t.Fatalf("co64 has %d entries, want %d", n, tt.samples)
+			}
+			for s, e := range stsz {
+				if e[0] != tt.size(s) {
+					t.Fatalf("sample %d is %d bytes, want %d", s, e[0], tt.size(s))
+				}
+				off := binary.BigEndian.Uint64(co64[4+s*8:])
+				if off < mdatStart || off+uint64(e[0]) > uint64(len(file)) {
+					t.Fatalf("sample %d at %d+%d lies outside mdat", s, off, e[0])
+				}
+				if s == 0 && !bytes.HasPrefix(file[off:], tt.first) {
+					t.Errorf("first sample starts %x, want %x", file[off:off+uint64(len(tt.first))], tt.first)
+				}
+			}
+		})
+	}
+}
+
+func TestTrackTimestamps(t *testing.T) {
+	f, err := os.Open(fixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	tracks := map[uint16]*trackBuilder{}
+	demux := mpegts.NewDemuxer(f)
+	for {
+		u, err := demux.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u.PID == privatePID {
+			continue
+		}
+		tb := tracks[u.PID]
+		if tb == nil {
+			tb = newTrackBuilder(u.PID, u.StreamType)
+			tracks[u.PID] = tb
+		}
+		samples, err := tb.push(u.PES)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, s := range samples {
+			tb.record(s, 0)
+		}
+	}
+
+	// the wrapped timestamps continue past 2^33 instead of restarting at 0
+	audioStart := (fixtureDTS + ptsDelay) * aacRate / tsClock
+	for _, c := range []struct {
+		name        string
+		pid         uint16
+		samples     int
+		first, last int64 // decode timestamps
+		step, cts   int64
+	}{
+		{"video", videoPID, videoFrames, fixtureDTS, fixtureDTS + (videoFrames-1)*frameTicks, frameTicks, ptsDelay},
+		{"audio", audioPID, aacFrames, audioStart, audioStart + (aacFrames-1)*aacSamples, aacSamples, 0},
+	} {
+		tb := tracks[c.pid]
+		if tb == nil || len(tb.dts) != c.samples {
+			t.Fatalf("%s: got %v, want %d samples", c.name, tb, c.samples)
+		}
+		if first, last := tb.dts[0], tb.dts[len(tb.dts)-1]; first != c.first || last != c.last {
+			t.Errorf("%s decode timestamps run %d..%d, want %d..%d", c.name, first, last, c.first, c.last)
+		}
+		for i := range tb.dts {
+			if i > 0 && tb.dts[i]-tb.dts[i-1] != c.step {
+				t.Errorf("%s sample %d: DTS step %d, want %d", c.name, i, tb.dts[i]-tb.dts[i-1], c.step)
+			}
+			if cts := tb.pts[i] - tb.dts[i]; cts != c.cts {
+				t.Errorf("%s sample %d: PTS-DTS = %d, want %d", c.name, i, cts, c.cts)
+			}
+		}
+	}
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Info(string)    {}
+func (nopLogger) Debug(string)   {}
+func (nopLogger) Warn(string)    {}
+func (nopLogger) Error(string)   {}
+func (nopLogger) Success(string) {}
@@ -0,0 +1,318 @@
+package remux
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/ajaysinghnp/maya-cli/internal/codec"
+	"github.com/ajaysinghnp/maya-cli/internal/mp4"
+	"github.com/ajaysinghnp/maya-cli/internal/mpegts"
+)
+
+const (
+	// timestamps in MPEG-TS are 33 bit, 90 kHz
+	tsWrap     = int64(1) << 33
+	tsClock    = 90000
+	aacSamples = 1024 // PCM samples per AAC frame
+
+	// a jump larger than this between two samples is treated as a discontinuity
+	maxGap = 10 * tsClock
+)
+
+// sample is an access unit ready to be written to the media payload
+type sample struct {
+	data []byte
+	dts  int64 // track timescale
+	pts  int64
+	sync bool
+}
+
+// trackBuilder turns the PES packets of one elementary stream into MP4 samples
+type trackBuilder struct {
+	pid        uint16
+	streamType uint8
+	timescale  uint32
+
+	// written samples (payload offsets are filled in by the caller)
+	samples []mp4.Sample
+	dts     []int64
+	pts     []int64
+
+	// video parameter sets
+	vps, sps, pps [][]byte
+	keyframeSeen  bool
+	paramsChanged bool
+
+	// audio config from the first ADTS header
+	adts *codec.ADTSHeader
+
+	// 90 kHz timestamp bookkeeping
+	wrap    int64
+	shift   int64
+	lastDTS int64
+	started bool
+
+	// next AAC frame timestamp in track timescale
+	next int64
+}
+
+func newTrackBuilder(pid uint16, streamType uint8) *trackBuilder {
+	return &trackBuilder{pid: pid, streamType: streamType, timescale: tsClock}
+}
+
+func (tb *trackBuilder) isVideo() bool {
+	return tb.streamType == mpegts.StreamTypeH264 || tb.streamType == mpegts.StreamTypeH265
+}
+
+// timestamps maps raw PES timestamps onto a continuous 90 kHz timeline,
+// undoing 33 bit wrap-arounds and closing gaps left by discontinuities
+func (tb *trackBuilder) timestamps(pes *mpegts.PES, frameDur int64) (int64, int64) {
+	if pes.PTS < 0 {
+		d := tb.lastDTS + frameDur
+		return d, d
+	}
+
+	dts, pts := pes.DTS+tb.wrap, pes.PTS+tb.wrap
+	if tb.started && dts < tb.lastDTS-tsWrap/2 {
+		tb.wrap += tsWrap
+		dts, pts = dts+tsWrap, pts+tsWrap
+	}
+	// the PTS may already have wrapped while the DTS hasn't
+	if pts < dts-tsWrap/2 {
+		pts += tsWrap
+	}
+
+	dts, pts = dts+tb.shift, pts+tb.shift
+	if tb.started && (dts <= tb.lastDTS || dts > tb.lastDTS+maxGap) {
+		adjust := tb.lastDTS + frameDur - dts
+		tb.shift += adjust
+		dts, pts = dts+adjust, pts+adjust
+	}
+
+	return dts, pts
+}
+
+// push converts one PES packet into zero or more samples
+func (tb *trackBuilder) push(pes *mpegts.PES) ([]sample, error) {
+	switch tb.streamType {
+	case mpegts.StreamTypeH264, mpegts.StreamTypeH265:
+		s, ok := tb.pushVideo(pes)
+		if !ok {
+			return nil, nil
+		}
+		return []sample{s}, nil
+	case mpegts.StreamTypeAAC:
+		return tb.pushAAC(pes)
+	}
+	return nil, nil
+}
+
+func (tb *trackBuilder) pushVideo(pes *mpegts.PES) (sample, bool) {
+	hevc := tb.streamType == mpegts.StreamTypeH265
+
+	var (
+		data bytes.Buffer
+		sync bool
+	)
+
+	for _, nal := range codec.SplitAnnexB(pes.Payload) {
+		if hevc {
+			switch codec.H265NALType(nal) {
+			case codec.H265NALAUD:
+				continue
+			case codec.H265NALVPS:
+				tb.vps = tb.addParam(tb.vps, nal)
+				continue
+			case codec.H265NALSPS:
+				tb.sps = tb.addParam(tb.sps, nal)
+				continue
+			case codec.H265NALPPS:
+				tb.pps = tb.addParam(tb.pps, nal)
+				continue
+			}
+			sync = sync || codec.H265IsKeyframe(nal)
+		} else {
+			switch nal[0] & 0x1F {
+			case codec.H264NALAUD:
+				continue
+			case codec.H264NALSPS:
+				tb.sps = tb.addParam(tb.sps, nal)
+				continue
+			case codec.H264NALPPS:
+				tb.pps = tb.addParam(tb.pps, nal)
+				continue
+			case codec.H264NALIDR:
+				sync = true
+			}
+		}
+
+		var size [4]byte
+		binary.BigEndian.PutUint32(size[:], uint32(len(nal)))
+		data.Write(size[:])
+		data.Write(nal)
+	}
+
+	if data.Len() == 0 {
+		return sample{}, false
+	}
+
+	// nothing before the first keyframe can be decoded
+	if !tb.keyframeSeen {
+		if !sync || len(tb.sps) == 0 || len(tb.pps) == 0 {
+			return sample{}, false
+		}
+		tb.keyframeSeen = true
+	}
+
+	dts, pts := tb.timestamps(pes, tb.lastDuration(3003))
+	tb.lastDTS, tb.started = dts, true
+
+	return sample{data: data.Bytes(), dts: dts, pts: pts, sync: sync}, true
+}
+
+func (tb *trackBuilder) pushAAC(pes *mpegts.PES) ([]sample, error) {
+	var out []sample
+
+	payload := pes.Payload
+	for first := true; len(payload) > 0; first = false {
+		h, err := codec.ParseADTSHeader(payload)
+		if err != nil {
+			return out, err
+		}
+		if h.FrameLen > len(payload) {
+			break // truncated frame at the end of the stream
+		}
+
+		if tb.adts == nil {
+			tb.adts = &h
+			tb.timescale = uint32(h.SampleRate())
+		}
+
+		frame := payload[h.HeaderLen:h.FrameLen]
+		payload = payload[h.FrameLen:]
+
+		// frames are counted; the PES timestamp only re-anchors the
+		// count when it drifts by a whole frame or more (gaps, splices)
+		ts := tb.next
+		if first && pes.PTS >= 0 {
+			dts, _ := tb.timestamps(pes, aacSamples*tsClock/int64(tb.timescale))
+			anchor := dts * int64(tb.timescale) / tsClock
+			if !tb.started || anchor-tb.next >= aacSamples || tb.next-anchor >= aacSamples {
+				ts = anchor
+			}
+		}
+
+		tb.next = ts + aacSamples
+		tb.lastDTS, tb.started = ts*tsClock/int64(tb.timescale), true
+
+		out = append(out, sample{data: frame, dts: ts, pts: ts, sync: true})
+	}
+
+	return out, nil
+}
+
+// addParam remembers a parameter set, flagging when the stream switches to
+// different ones (which a single sample entry cannot describe)
+func (tb *trackBuilder) addParam(list [][]byte, nal []byte) [][]byte {
+	for _, p := range list {
+		if bytes.Equal(p, nal) {
+			return list
+		}
+	}
+	if len(list) > 0 && !tb.keyframeSeen {
+		// a newer set before the first keyframe replaces the old one
+		return [][]byte{append([]byte(nil), nal...)}
+	}
+	if len(list) > 0 {
+		tb.paramsChanged = true
+	}
+	return append(list, append([]byte(nil), nal...))
+}
+
+// lastDuration returns the most recent sample duration, or def when unknown
+func (tb *trackBuilder) lastDuration(def int64) int64 {
+	if n := len(tb.dts); n >= 2 {
+		return tb.dts[n-1] - tb.dts[n-2]
+	}
+	return def
+}
+
+// record stores a sample written at offset in the media payload
+func (tb *trackBuilder) record(s sample, offset int64) {
+	tb.samples = append(tb.samples, mp4.Sample{
+		Offset: offset,
+		Size:   uint32(len(s.data)),
+		Sync:   s.sync,
+	})
+	tb.dts = append(tb.dts, s.dts)
+	tb.pts = append(tb.pts, s.pts)
+}
+
+// track finalises durations and composition offsets into an mp4.Track
+func (tb *trackBuilder) track() (*mp4.Track, error) {
+	n := len(tb.samples)
+	if n == 0 {
+		return nil, fmt.Errorf("stream 0x%x has no usable samples", tb.pid)
+	}
+
+	for i := range tb.samples {
+		var d int64
+		switch {
+		case i+1 < n:
+			d = tb.dts[i+1] - tb.dts[i]
+		case i > 0:
+			d = tb.dts[i] - tb.dts[i-1]
+		case tb.isVideo():
+			d = 3003
+		default:
+			d = aacSamples
+		}
+		if d <= 0 {
+			d = 1
+		}
+		tb.samples[i].Duration = uint32(d)
+
+		if cts := tb.pts[i] - tb.dts[i]; cts > 0 {
+			tb.samples[i].CTS = uint32(cts)
+		}
+	}
+
+	t := &mp4.Track{
+		Timescale: tb.timescale,
+		Samples:   tb.samples,
+		MediaTime: int64(tb.samples[0].CTS),
+	}
+
+	switch tb.streamType {
+	case mpegts.StreamTypeH264:
+		sps, err := codec.ParseH264SPS(tb.sps[0])
+		if err != nil {
+			return nil, err
+		}
+		t.Handler, t.Width, t.Height = "vide", sps.Width, sps.Height
+		t.SampleEntry = mp4.AVCSampleEntry(sps, tb.sps, tb.pps)
+
+	case mpegts.StreamTypeH265:
+		if len(tb.vps) == 0 {
+			return nil, fmt.Errorf("stream 0x%x: HEVC stream without VPS", tb.pid)
+		}
+		sps, err := codec.ParseH265SPS(tb.sps[0])
+		if err != nil {
+			return nil, err
+		}
+		t.Handler, t.Width, t.Height = "vide", sps.Width, sps.Height
+		t.SampleEntry = mp4.HEVCSampleEntry(sps, tb.vps, tb.sps, tb.pps)
+
+	case mpegts.StreamTypeAAC:
+		t.Handler = "soun"
+		t.SampleEntry = mp4.AACSampleEntry(tb.adts.AudioSpecificConfig(), tb.adts.SampleRate(), tb.adts.Channels)
+	}
+
+	return t, nil
+}
+
+// start returns the presentation time of the first sample in seconds
+func (tb *trackBuilder) start() float64 {
+	return float64(tb.pts[0]) / float64(tb.timescale)
+}
@@ -0,0 +1,241 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ajaysinghnp/maya-cli/internal/logger/iface"
+)
+
+// DefaultRetryOn lists the HTTP statuses worth trying again
+var DefaultRetryOn = []int{
+	http.StatusRequestTimeout,
+	http.StatusTooEarly,
+	http.StatusTooManyRequests,
+	http.StatusInternalServerError,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// Policy decides how often and how patiently failed requests are retried
+type Policy struct {
+	Attempts  int           // total tries including the first, < 1 means 1
+	BaseDelay time.Duration // wait before the first retry, doubled on every further one
+	MaxDelay  time.Duration // upper bound for the backoff (not for Retry-After)
+	RetryOn   []int         // HTTP statuses that are retried
+	Timeout   time.Duration // limit for a single attempt including the body, 0 = none
+
+	Log iface.Logger // optional, reports every retry
+}
+
+// Default returns the policy used when nothing is configured
+func Default() Policy {
+	return Policy{
+		Attempts:  4,
+		BaseDelay: time.Second,
+		MaxDelay:  30 * time.Second,
+		RetryOn:   DefaultRetryOn,
+		Timeout:   2 * time.Minute,
+	}
+}
+
+// StatusError reports an HTTP response with an unwanted status
+type StatusError struct {
+	Code       int
+	RetryAfter time.Duration // from the Retry-After header, 0 when absent
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected HTTP status: %d", e.Code)
+}
+
+// permanentError marks an error that must not be retried
+type permanentError struct{ err error }
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so Do gives up immediately
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err}
+}
+
+// NewStatusError builds a StatusError from a response, reading its Retry-After
+func NewStatusError(resp *http.Response) *StatusError {
+	return &StatusError{
+		Code:       resp.StatusCode,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+}
+
+// Do runs op until it succeeds, fails permanently or the attempts run out.
+// Each attempt gets its own context bounded by the policy timeout.
+func (p Policy) Do(ctx context.Context, op func(ctx context.Context) error) error {
+	return p.run(ctx, func() error {
+		actx, cancel := p.attemptContext(ctx)
+		defer cancel()
+		return op(actx)
+	})
+}
+
+// Request sends req with client, retrying network errors and the statuses in
+// RetryOn. Any other status is returned to the caller as a normal response.
+// The per-attempt timeout also covers reading the returned body.
+func (p Policy) Request(client *http.Client, req *http.Request) (*http.Response, error) {
+	var resp *http.Response
+
+	err := p.run(req.Context(), func() error {
+		ctx, cancel := p.attemptContext(req.Context())
+
+		r, err := client.Do(req.Clone(ctx))
+		if err != nil {
+			cancel()
+			return err
+		}
+
+		if p.retryable(r.StatusCode) {
+			io.Copy(io.Discard, io.LimitReader(r.Body, 64*1024))
+			r.Body.Close()
+			cancel()
+			return NewStatusError(r)
+		}
+
+		r.Body = &cancelBody{ReadCloser: r.Body, cancel: cancel}
+		resp = r
+		return nil
+	})
+
+	return resp, err
+}
+
+// run is the retry loop shared by Do and Request
+func (p Policy) run(ctx context.Context, attempt func() error) error {
+	attempts := p.Attempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	for n := 1; ; n++ {
+		err := attempt()
+		if err == nil {
+			return nil
+		}
+
+		var perm *permanentError
+		if errors.As(err, &perm) {
+			return perm.err
+		}
+
+		var status *StatusError
+		if errors.As(err, &status) && !p.retryable(status.Code) {
+			return err
+		}
+
+		// the caller gave up, not the server
+		if ctx.Err() != nil {
+			return err
+		}
+
+		if n >= attempts {
+			if attempts > 1 {
+				return fmt.Errorf("giving up after %d attempts: %w", attempts, err)
+			}
+			return err
+		}
+
+		delay := p.backoff(n)
+		if status != nil && status.RetryAfter > 0 {
+			delay = status.RetryAfter
+		}
+
+		if p.Log != nil {
+			p.Log.Warn(fmt.Sprintf("Attempt %d/%d failed (%v), retrying in %s",
+				n, attempts, err, delay.Round(time.Millisecond)))
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// backoff returns the wait after the n-th failed attempt: exponential growth
+// capped at MaxDelay, with jitter so parallel workers don't retry in lockstep
+func (p Policy) backoff(n int) time.Duration {
+	if p.BaseDelay <= 0 {
+		return 0
+	}
+
+	d := p.BaseDelay
+	for i := 1; i < n && (p.MaxDelay <= 0 || d < p.MaxDelay); i++ {
+		d *= 2
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+
+	// somewhere between half and the full delay
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+func (p Policy) retryable(code int) bool {
+	for _, c := range p.RetryOn {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+func (p Policy) attemptContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if p.Timeout > 0 {
+		return context.WithTimeout(ctx, p.Timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// parseRetryAfter understands both delay-seconds and HTTP-date values
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// cancelBody releases the attempt context once the body is closed
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,56 @@
+package utils
+
+import "strings"
+
+// languages lists the ISO 639-2 code first, followed by aliases (ISO 639-1,
+// bibliographic codes and English names) for the languages we commonly see
+var languages = [][]string{
+	{"eng", "en", "english"},
+	{"hin", "hi", "hindi"},
+	{"nep", "ne", "nepali"},
+	{"tam", "ta", "tamil"},
+	{"tel", "te", "telugu"},
+	{"mal", "ml", "malayalam"},
+	{"kan", "kn", "kannada"},
+	{"ben", "bn", "bengali", "bangla"},
+	{"mar", "mr", "marathi"},
+	{"guj", "gu", "gujarati"},
+	{"pan", "pa", "punjabi"},
+	{"urd", "ur", "urdu"},
+	{"spa", "es", "spanish"},
+	{"fra", "fr", "fre", "french"},
+	{"deu", "de", "ger", "german"},
+	{"ita", "it", "italian"},
+	{"por", "pt", "portuguese"},
+	{"rus", "ru", "russian"},
+	{"ara", "ar", "arabic"},
+	{"jpn", "ja", "japanese"},
+	{"kor", "ko", "korean"},
+	{"zho", "zh", "chi", "chinese"},
+}
+
+// LanguageCode returns the ISO 639-2 code for a language code or name
+// ("hi", "hin", "Hindi" → "hin"). Unknown values are returned lowercased.
+func LanguageCode(v string) string {
+	v = strings.ToLower(strings.TrimSpace(v))
+
+	// region subtags like en-US don't matter for matching
+	if base, _, ok := strings.Cut(v, "-"); ok {
+		v = base
+	}
+
+	for _, l := range languages {
+		for _, alias := range l {
+			if v == alias {
+				return l[0]
+			}
+		}
+	}
+	return v
+}
+
+// SameLanguage reports whether two language codes or names refer to the same language
+func SameLanguage(a, b string) bool {
+	a, b = LanguageCode(a), LanguageCode(b)
+	return a != "" && a == b
+}